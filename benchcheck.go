@@ -1,22 +1,61 @@
 package benchcheck
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"golang.org/x/perf/benchfmt"
 	"golang.org/x/perf/benchstat"
 )
 
 // CheckerFmt represents the expected string format of a checker.
-const CheckerFmt = "<metric>=(+|-)<number>%"
+// The optional benchmark regular expression restricts the checker to
+// the benchmarks whose names match it, as in "go test -bench".
+//
+// A threshold like "time/op=+10%" is a percent delta between the old and
+// new benchmarks, the "%" being optional, so "time/op=+10" is the same.
+// A threshold with a unit, like "time/op=+1ms" or "allocs/op=+1allocs",
+// is an absolute delta. The threshold can also be an absolute bound on
+// the new benchmarks, like "allocs/op<=2" or "time/op<1ms", using the
+// "<", "<=", ">" and ">=" operators, where values without unit are on
+// the unit of the benchmark results.
+// Units must be valid for the metric, like ns, µs, ms and s for time/op,
+// B, kB and MB for alloc/op or allocs for allocs/op, while metrics with
+// unknown units only accept SI prefixes, like k and M.
+const CheckerFmt = "[<benchmark regex>:]<metric>(=(+|-)<number>[%|unit]|(<|<=|>|>=)<number>[unit])"
+
+// Metrics available on StatResult, as named by benchstat.
+const (
+	// TimeMetric is the time per operation, from ns/op.
+	TimeMetric = "time/op"
+	// AllocMetric is the bytes allocated per operation, from B/op.
+	AllocMetric = "alloc/op"
+	// AllocsMetric is the allocations per operation, from allocs/op.
+	AllocsMetric = "allocs/op"
+	// SpeedMetric is the throughput, from MB/s.
+	SpeedMetric = "speed"
+)
+
+// geoMeanRow is the name benchstat gives to the geometric mean row.
+const geoMeanRow = "[Geo mean]"
 
 // Module represents a Go module.
 type Module struct {
-	path string
+	path    string
+	cleanup func() error
 }
 
 // StatResult is the full result showing performance
@@ -24,34 +63,151 @@ type Module struct {
 // for a specific metric, like time/op or speed.
 type StatResult struct {
 	// Metric is the name of metric
-	Metric string
+	Metric string `json:"metric"`
 	// BenchDiffs has the performance diff of all function for a given metric.
-	BenchDiffs []BenchDiff
+	BenchDiffs []BenchDiff `json:"bench_diffs"`
+	// Added has the benchmarks of the metric that are only on the new results.
+	Added []BenchName `json:"added,omitempty"`
+	// Removed has the benchmarks of the metric that are only on the old
+	// results, like deleted or renamed benchmarks, which can hide regressions.
+	Removed []BenchName `json:"removed,omitempty"`
+}
+
+// BenchName identifies a benchmark function, which may have
+// the same name of benchmarks on other packages.
+type BenchName struct {
+	// Package of the benchmark function, empty if the results
+	// have no package, like BenchDiff.Package.
+	Package string `json:"package,omitempty"`
+	// Name of the benchmark function.
+	Name string `json:"name"`
 }
 
-// BenchResults represents a single Go benchmark run. Each
-// string is the result of a single Benchmark like this:
+// BenchResults represents a single Go benchmark run, as lines of the
+// Go benchmark format. Each string is either the result of a single
+// Benchmark or a configuration line applying to the results after it,
+// like these:
+// - "pkg: example.com/encoding"
 // - "BenchmarkName  	 50	  31735022 ns/op	  61.15 MB/s"
+//
+// Configuration lines, like goos, goarch, pkg and cpu, are kept so
+// benchmarks with the same name on different packages are not mixed.
 type BenchResults []string
 
 // BenchDiff is the result showing performance differences
 // for a single benchmark function.
 type BenchDiff struct {
 	// Name of the benchmark function
-	Name string
+	Name string `json:"name"`
+	// Package of the benchmark function, from the "pkg" configuration
+	// of the results. It is empty if the results have no package.
+	Package string `json:"package,omitempty"`
 	// Old is the performance summary of the old benchmark.
-	Old string
+	Old string `json:"old"`
 	// New is the performance summary of the new benchmark.
-	New string
+	New string `json:"new"`
 	// Delta between the old and new performance summaries.
-	Delta float64
+	Delta float64 `json:"delta"`
+	// Unit of the benchmark values, like ns/op or B/op.
+	Unit string `json:"unit"`
+	// OldStats are the statistics of the old benchmark samples.
+	OldStats BenchStats `json:"old_stats"`
+	// NewStats are the statistics of the new benchmark samples.
+	NewStats BenchStats `json:"new_stats"`
+	// PValue is the p-value of the statistical test comparing the
+	// old and new samples. It is -1 if the test couldn't be performed,
+	// like when there are too few samples.
+	PValue float64 `json:"p_value"`
+	// Significant is true if the difference between the old and new
+	// samples is statistically significant. Delta is zero for differences
+	// that are not significant, except on the "[Geo mean]" row which
+	// has no samples to be tested.
+	Significant bool `json:"significant"`
+}
+
+// BenchStats are the statistics of the samples of a single benchmark.
+// Outliers are removed from the samples before computing statistics.
+type BenchStats struct {
+	// Mean of the samples, on the unit of the benchmark.
+	Mean float64 `json:"mean"`
+	// Median of the samples, on the unit of the benchmark.
+	Median float64 `json:"median"`
+	// Min is the smallest sample, on the unit of the benchmark.
+	Min float64 `json:"min"`
+	// Max is the largest sample, on the unit of the benchmark.
+	Max float64 `json:"max"`
+	// Variation is the largest percent variation of min and max
+	// compared to the mean, the "±" on the formatted summaries.
+	Variation float64 `json:"variation"`
+	// Samples is the number of samples.
+	Samples int `json:"samples"`
 }
 
 // Checker performs checks on StatResult.
 type Checker struct {
-	metric    string
-	threshold float64
-	repr      string
+	pattern             *BenchRegexp
+	metric              string
+	kind                ThresholdKind
+	op                  string
+	threshold           float64
+	limit               string
+	repr                string
+	requireSignificance bool
+	failInconclusive    bool
+}
+
+// ThresholdKind is how the threshold of a checker is compared to benchmarks.
+type ThresholdKind string
+
+const (
+	// PercentDelta thresholds limit the percent delta between old and new.
+	PercentDelta ThresholdKind = "percent_delta"
+	// AbsoluteDelta thresholds limit the absolute delta between old and new.
+	AbsoluteDelta ThresholdKind = "absolute_delta"
+	// AbsoluteBound thresholds limit the absolute value of new.
+	AbsoluteBound ThresholdKind = "absolute_bound"
+)
+
+// CheckStatus is the outcome of a check.
+type CheckStatus string
+
+const (
+	// CheckPassed means no benchmark violated the checker.
+	CheckPassed CheckStatus = "passed"
+	// CheckFailed means at least one benchmark violated the checker.
+	CheckFailed CheckStatus = "failed"
+	// CheckInconclusive means no benchmark violated the checker but
+	// some exceeded the threshold without statistical significance.
+	CheckInconclusive CheckStatus = "inconclusive"
+)
+
+// CheckReport is the result of evaluating a Checker on a StatResult.
+type CheckReport struct {
+	// Checker is the evaluated checker.
+	Checker Checker
+	// Metric is the metric of the evaluated StatResult.
+	Metric string
+	// Violations has all benchmarks that violated the checker.
+	Violations []Violation
+	// Inconclusive has all benchmarks that exceeded the threshold of the
+	// checker without statistical significance. It is only populated by
+	// checkers that require significance, see Checker.RequireSignificance.
+	Inconclusive []Violation
+}
+
+// Violation represents a single benchmark that violated a Checker.
+type Violation struct {
+	// BenchDiff is the performance diff of the offending benchmark.
+	BenchDiff
+	// Threshold is the threshold that was violated. It is a percent
+	// for percent deltas, otherwise it is on the unit of the benchmark.
+	Threshold float64 `json:"threshold"`
+	// Limit is the human readable threshold that was violated,
+	// like "+10%", "+1" or "<=1ms".
+	Limit string `json:"limit"`
+	// Kind is the kind of the threshold that was violated.
+	// If empty it is a PercentDelta.
+	Kind ThresholdKind `json:"kind"`
 }
 
 // CmdError represents an error running a specific command.
@@ -76,22 +232,217 @@ func (c Checker) String() string {
 	return c.repr
 }
 
+// Metric returns the name of the metric handled by the checker.
+func (c Checker) Metric() string {
+	return c.metric
+}
+
+// Matches returns true if the checker applies to the benchmark with the
+// given name, as reported on results, like "Encode-8". The benchmark
+// regular expression is matched like "go test -bench" does, see
+// BenchRegexp. Checkers without a benchmark regular expression match
+// all benchmarks.
+func (c Checker) Matches(name string) bool {
+	if c.pattern == nil {
+		return true
+	}
+	return c.pattern.MatchString(name)
+}
+
+// benchRegexp returns the benchmark regular expression of the
+// checker, empty if it has none.
+func (c Checker) benchRegexp() string {
+	if c.pattern == nil {
+		return ""
+	}
+	return c.pattern.String()
+}
+
+// RequireSignificance returns a copy of the checker that only reports
+// violations on statistically significant differences. Benchmarks whose
+// difference of means exceeds the threshold without being significant,
+// like when there are too few samples or too much variance, are reported
+// as inconclusive instead of silently passing. If failInconclusive is
+// true, inconclusive benchmarks also fail the check.
+func (c Checker) RequireSignificance(failInconclusive bool) Checker {
+	c.requireSignificance = true
+	c.failInconclusive = failInconclusive
+	return c
+}
+
+// FailsInconclusive returns true if benchmarks reported as inconclusive
+// fail the check.
+func (c Checker) FailsInconclusive() bool {
+	return c.failInconclusive
+}
+
 // Do performs the check on the given StatResult. Returns true
 // if it passed the check, false otherwise.
 func (c Checker) Do(stat StatResult) bool {
+	return c.Evaluate(stat).Passed()
+}
+
+// Evaluate performs the check on the given StatResult, reporting
+// all benchmarks that violated the checker. A StatResult of a metric
+// that is not handled by the checker always passes, just like
+// benchmarks that don't match the checker, see Checker.Matches.
+func (c Checker) Evaluate(stat StatResult) CheckReport {
+	report := CheckReport{
+		Checker: c,
+		Metric:  stat.Metric,
+	}
 	if c.metric != stat.Metric {
-		return true
+		return report
 	}
 
 	for _, bench := range stat.BenchDiffs {
-		if c.threshold >= 0.0 && bench.Delta > c.threshold {
-			return false
+		if !c.Matches(bench.Name) {
+			continue
 		}
-		if c.threshold < 0.0 && bench.Delta < c.threshold {
-			return false
+
+		violation := Violation{
+			BenchDiff: bench,
+			Threshold: c.threshold,
+			Limit:     c.limit,
+			Kind:      c.kind,
+		}
+
+		// Bounds don't compare old and new, so significance doesn't matter.
+		if c.kind == AbsoluteBound {
+			if c.outOfBounds(bench.NewStats.Mean) {
+				report.Violations = append(report.Violations, violation)
+			}
+			continue
+		}
+
+		if !c.requireSignificance {
+			if c.violated(c.delta(bench, false)) {
+				report.Violations = append(report.Violations, violation)
+			}
+			continue
+		}
+
+		if !c.violated(c.delta(bench, true)) {
+			continue
+		}
+		if bench.Significant {
+			report.Violations = append(report.Violations, violation)
+		} else {
+			report.Inconclusive = append(report.Inconclusive, violation)
 		}
 	}
-	return true
+	return report
+}
+
+// delta returns the delta of the benchmark compared by the checker.
+// If means is false non significant deltas are zero, like benchstat does,
+// otherwise the delta of the means is used even if not significant.
+func (c Checker) delta(bench BenchDiff, means bool) float64 {
+	if c.kind == PercentDelta {
+		if means {
+			return bench.MeanDelta()
+		}
+		return bench.Delta
+	}
+	if !means && bench.Delta == 0 {
+		return 0
+	}
+	return bench.NewStats.Mean - bench.OldStats.Mean
+}
+
+func (c Checker) violated(delta float64) bool {
+	if c.threshold >= 0.0 {
+		return delta > c.threshold
+	}
+	return delta < c.threshold
+}
+
+func (c Checker) outOfBounds(val float64) bool {
+	switch c.op {
+	case "<":
+		return val >= c.threshold
+	case "<=":
+		return val > c.threshold
+	case ">":
+		return val <= c.threshold
+	case ">=":
+		return val < c.threshold
+	}
+	return false
+}
+
+// Passed returns true if no benchmarks violated the checker. Inconclusive
+// benchmarks only fail the check if the checker was configured to.
+func (r CheckReport) Passed() bool {
+	return r.Status() != CheckFailed
+}
+
+// Status returns the outcome of the check.
+func (r CheckReport) Status() CheckStatus {
+	if len(r.Violations) > 0 {
+		return CheckFailed
+	}
+	if len(r.Inconclusive) > 0 {
+		if r.Checker.failInconclusive {
+			return CheckFailed
+		}
+		return CheckInconclusive
+	}
+	return CheckPassed
+}
+
+// String returns the string representation of the report, with
+// one line per violation and inconclusive benchmark.
+func (r CheckReport) String() string {
+	status := r.Status()
+	if status == CheckPassed {
+		return fmt.Sprintf("check passed: %s", r.Checker)
+	}
+	lines := make([]string, 0, len(r.Violations)+len(r.Inconclusive)+1)
+	lines = append(lines, fmt.Sprintf("check %s: %s", status, r.Checker))
+	for _, v := range r.Violations {
+		lines = append(lines, "\t"+v.String())
+	}
+	for _, v := range r.Inconclusive {
+		lines = append(lines, "\t"+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String returns the string representation of the violation.
+func (v Violation) String() string {
+	if v.Kind == AbsoluteDelta || v.Kind == AbsoluteBound {
+		return fmt.Sprintf("%s: %s", v.BenchDiff, v.describe())
+	}
+	if !v.Significant && v.Delta == 0 {
+		return fmt.Sprintf("%s: mean delta: %+.2f%%: not significant: threshold: %+.2f%%",
+			v.BenchDiff, v.MeanDelta(), v.Threshold)
+	}
+	return fmt.Sprintf("%s: threshold: %+.2f%%", v.BenchDiff, v.Threshold)
+}
+
+// describe describes how the benchmark violated the threshold.
+func (v Violation) describe() string {
+	switch v.Kind {
+	case AbsoluteBound:
+		return fmt.Sprintf("new %s violates %s",
+			formatQuantity(v.NewStats.Mean, v.Unit), v.Limit)
+	case AbsoluteDelta:
+		delta := v.NewStats.Mean - v.OldStats.Mean
+		sign := "+"
+		if delta < 0 {
+			sign = ""
+		}
+		description := fmt.Sprintf("delta %s%s exceeds %s", sign, formatQuantity(delta, v.Unit), v.Limit)
+		if !v.Significant && v.Delta == 0 {
+			description += " but is not significant"
+		}
+		return description
+	}
+	if !v.Significant && v.Delta == 0 {
+		return fmt.Sprintf("mean delta %+.2f%% exceeds %+.2f%% but is not significant", v.MeanDelta(), v.Threshold)
+	}
+	return fmt.Sprintf("delta %s exceeds %+.2f%%", formatDelta(v.Delta), v.Threshold)
 }
 
 // Path is the absolute path of the module on the filesystem.
@@ -104,6 +455,28 @@ func (m Module) String() string {
 	return fmt.Sprintf("go module at %q", m.path)
 }
 
+// Close releases any resources associated with the module, like
+// temporary git worktrees. Modules obtained from the Go module cache
+// have nothing to release, so it is always safe to call Close.
+func (m Module) Close() error {
+	if m.cleanup == nil {
+		return nil
+	}
+	return m.cleanup()
+}
+
+// Packages returns the packages of the benchmarks of the result,
+// without duplicates, in order of appearance.
+func (r StatResult) Packages() []string {
+	var pkgs []string
+	for _, diff := range r.BenchDiffs {
+		if diff.Package != "" {
+			addString(&pkgs, diff.Package)
+		}
+	}
+	return pkgs
+}
+
 // String provides the string representation of a bench result
 func (b BenchDiff) String() string {
 	return fmt.Sprintf(
@@ -112,13 +485,75 @@ func (b BenchDiff) String() string {
 	)
 }
 
-// Add will add a new bench result. If the string doesn't represent
-// a benchmark result it will be ignored.
+// fullName returns the name of the benchmark qualified by its
+// package, if any, so it is unique among all benchmarks.
+func (b BenchDiff) fullName() string {
+	return BenchName{Package: b.Package, Name: b.Name}.String()
+}
+
+// String returns the name of the benchmark qualified by its
+// package, if any, like "example.com/encoding.Decode".
+func (b BenchName) String() string {
+	if b.Package == "" {
+		return b.Name
+	}
+	return b.Package + "." + b.Name
+}
+
+// MeanDelta returns the percent difference between the old and new
+// means, even if it is not statistically significant, in which case
+// Delta is zero.
+func (b BenchDiff) MeanDelta() float64 {
+	if b.Significant || b.OldStats.Mean == 0 {
+		return b.Delta
+	}
+	return (b.NewStats.Mean/b.OldStats.Mean - 1) * 100
+}
+
+// Add will add a new bench result or configuration line. If the
+// string doesn't represent any of them it will be ignored.
 func (b *BenchResults) Add(res string) {
+	b.parse(res)
+}
+
+// parse adds the line like Add, returning true if it was a bench result.
+func (b *BenchResults) parse(res string) bool {
+	if isConfigLine(res) {
+		*b = append(*b, res)
+		return false
+	}
 	if !strings.HasPrefix(res, "Benchmark") {
-		return
+		return false
+	}
+	// Results have at least the name, iterations and one metric,
+	// so lines like "BenchmarkX-8 panic: boom" are not results.
+	fields := strings.Fields(res)
+	if len(fields) < 4 {
+		return false
+	}
+	if n, err := strconv.Atoi(fields[1]); err != nil || n <= 0 {
+		return false
 	}
 	*b = append(*b, res)
+	return true
+}
+
+// isConfigLine returns true if the line is a configuration line of
+// the Go benchmark format, like "pkg: example.com/encoding", with a
+// key starting with a lower case letter and no spaces or upper case
+// letters, followed by a colon and a space separated value.
+func isConfigLine(line string) bool {
+	sep := strings.Index(line, ":")
+	if first, _ := utf8.DecodeRuneInString(line); sep <= 0 || !unicode.IsLower(first) {
+		return false
+	}
+	for _, r := range line[:sep] {
+		if unicode.IsSpace(r) || unicode.IsUpper(r) {
+			return false
+		}
+	}
+	value := line[sep+1:]
+	return value == "" || value[0] == ' ' || value[0] == '\t'
 }
 
 // GetModule will download a specific version of a module and
@@ -130,16 +565,17 @@ func (b *BenchResults) Add(res string) {
 // Any errors running "go" can be inspected in detail by
 // checking if the returned error is a *CmdError.
 func GetModule(name string, version string) (Module, error) {
-	// Reference: https://golang.org/ref/mod#go-mod-download
-	cmd := exec.Command("go", "mod", "download", "-json", fmt.Sprintf("%s@%s", name, version))
-	output, err := cmd.CombinedOutput()
+	return GetModuleContext(context.Background(), name, version)
+}
 
+// GetModuleContext works like GetModule, but "go" is killed if the
+// context is done before it finishes, returning a *CanceledError.
+func GetModuleContext(ctx context.Context, name string, version string) (Module, error) {
+	// Reference: https://golang.org/ref/mod#go-mod-download
+	cmd := command(ctx, "go", "mod", "download", "-json", fmt.Sprintf("%s@%s", name, version))
+	output, err := combinedOutput(ctx, cmd)
 	if err != nil {
-		return Module{}, &CmdError{
-			Cmd:    cmd,
-			Err:    err,
-			Output: string(output),
-		}
+		return Module{}, err
 	}
 
 	parsedResult := struct {
@@ -153,53 +589,133 @@ func GetModule(name string, version string) (Module, error) {
 	return Module{path: parsedResult.Dir}, nil
 }
 
-// RunBench will run all benchmarks present at the given module
-// return the benchmark results.
+// LocalModule returns the module at the given directory as it is,
+// including any uncommitted changes. Nothing is copied or downloaded,
+// so benchmarks will run directly on dir.
+// The returned module path is an absolute path.
+func LocalModule(dir string) (Module, error) {
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return Module{}, fmt.Errorf("getting absolute path of %q: %v", dir, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return Module{}, fmt.Errorf("local module: %v", err)
+	}
+	if !info.IsDir() {
+		return Module{}, fmt.Errorf("local module: %q is not a directory", path)
+	}
+	return Module{path: path}, nil
+}
+
+// RunBench will run the benchmarks of the given module and return
+// the benchmark results. By default all benchmarks of all packages
+// run, use WithRunOptions to select which benchmarks run and how.
+// Other options are ignored, except for WithRunner.
 //
-// This function relies on running the "go" command to run benchmarks.
+// Unless another Runner is provided, this function relies on running
+// the "go" command to run benchmarks.
 //
 // Any errors running "go" can be inspected in detail by
-// checking if the returned is a *CmdError.
-func RunBench(mod Module) (BenchResults, error) {
-	cmd := exec.Command("go", "test", "-bench=.", "./...")
-	cmd.Dir = mod.Path()
+// checking if the returned is a *CmdError. With RunOptions.Tolerant
+// the results of the packages that succeeded are returned along
+// with a *PartialError recording the failed packages.
+func RunBench(mod Module, opts ...Option) (BenchResults, error) {
+	return RunBenchContext(context.Background(), mod, opts...)
+}
 
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, &CmdError{
-			Cmd:    cmd,
-			Err:    err,
-			Output: string(out),
-		}
-	}
-	benchOut := strings.Split(string(out), "\n")
-	results := BenchResults{}
-	for _, b := range benchOut {
-		results.Add(b)
+// RunBenchContext works like RunBench, but "go test" and the benchmarks
+// it runs are killed if the context is done before they finish,
+// returning a *CanceledError.
+func RunBenchContext(ctx context.Context, mod Module, opts ...Option) (BenchResults, error) {
+	cfg := newConfig(opts)
+	if cfg.runner != nil {
+		return cfg.runner.Run(ctx, mod, cfg.run)
 	}
-	return results, nil
+	return GoTestRunner{}.Run(ctx, mod, cfg.run)
 }
 
 // Stat compares two benchmark results providing a set of stats results.
-func Stat(oldres BenchResults, newres BenchResults) ([]StatResult, error) {
-	// We are using benchstat defaults:
-	//	- https://cs.opensource.google/go/x/perf/+/master:cmd/benchstat/main.go;l=117
-	const (
-		alpha   = 0.05
-		geomean = false
-	)
+// By default it uses the same defaults as benchstat, use WithStatOptions
+// to configure the statistical test, alpha and geometric means.
+// Other options are ignored, since nothing runs.
+func Stat(oldres BenchResults, newres BenchResults, opts ...Option) ([]StatResult, error) {
+	return stat(oldres, newres, newConfig(opts).stat)
+}
+
+func stat(oldres BenchResults, newres BenchResults, opts StatOptions) ([]StatResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	deltaTest, _ := opts.Test.deltaTest()
 	c := &benchstat.Collection{
-		Alpha:      alpha,
-		AddGeoMean: geomean,
-		DeltaTest:  benchstat.UTest,
+		Alpha:      opts.alpha(),
+		AddGeoMean: opts.GeoMean,
+		DeltaTest:  deltaTest,
 	}
-	if err := c.AddFile("old", resultsReader(oldres)); err != nil {
+	if err := addResults(c, "old", oldres); err != nil {
 		return nil, fmt.Errorf("parsing old results: %v", err)
 	}
-	if err := c.AddFile("new", resultsReader(newres)); err != nil {
+	if err := addResults(c, "new", newres); err != nil {
 		return nil, fmt.Errorf("parsing new results: %v", err)
 	}
-	return newStatResults(c.Tables()), nil
+	return newStatResults(c, deltaTest), nil
+}
+
+// ParseBenchResults parses the output of "go test -bench" from
+// the given reader, ignoring anything that is not a benchmark result.
+func ParseBenchResults(r io.Reader) (BenchResults, error) {
+	results := BenchResults{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		results.Add(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading bench results: %v", err)
+	}
+	return results, nil
+}
+
+// StatFiles compares two saved outputs of "go test -bench",
+// like files produced by previous CI jobs, without running anything.
+// The options are the same accepted by Stat.
+func StatFiles(oldr, newr io.Reader, opts ...Option) ([]StatResult, error) {
+	oldres, err := ParseBenchResults(oldr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing old results: %w", err)
+	}
+	newres, err := ParseBenchResults(newr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing new results: %w", err)
+	}
+	return Stat(oldres, newres, opts...)
+}
+
+// AddedBenchmarks returns the benchmarks added on any metric of
+// the results, without duplicates, in order of appearance.
+func AddedBenchmarks(results []StatResult) []BenchName {
+	return uniqueBenchmarks(results, func(r StatResult) []BenchName { return r.Added })
+}
+
+// RemovedBenchmarks returns the benchmarks removed on any metric of
+// the results, without duplicates, in order of appearance.
+func RemovedBenchmarks(results []StatResult) []BenchName {
+	return uniqueBenchmarks(results, func(r StatResult) []BenchName { return r.Removed })
+}
+
+func uniqueBenchmarks(results []StatResult, benchmarks func(StatResult) []BenchName) []BenchName {
+	var unique []BenchName
+	seen := map[BenchName]bool{}
+	for _, result := range results {
+		for _, name := range benchmarks(result) {
+			if !seen[name] {
+				seen[name] = true
+				unique = append(unique, name)
+			}
+		}
+	}
+	return unique
 }
 
 // StatModule will:
@@ -208,56 +724,282 @@ func Stat(oldres BenchResults, newres BenchResults) ([]StatResult, error) {
 // - Run benchmarks on each of them.
 // - Compare old vs new version benchmarks and return a stat results.
 //
-// This function relies on running the "go" command to run benchmarks.
+// Options can be provided to configure how the benchmarks run,
+// including a custom Runner with WithRunner.
+//
+// This function relies on running the "go" command to download modules
+// and, unless another Runner is provided, to run benchmarks.
 //
 // Any errors running "go" can be inspected in detail by
 // checking if the returned error is a CmdError.
-func StatModule(name string, oldversion, newversion string) ([]StatResult, error) {
-	oldresults, err := benchModule(name, oldversion)
+func StatModule(name string, oldversion, newversion string, opts ...Option) ([]StatResult, error) {
+	return StatModuleContext(context.Background(), name, oldversion, newversion, opts...)
+}
+
+// StatModuleContext works like StatModule, but all commands are killed
+// if the context is done before they finish, returning an error that
+// wraps a *CanceledError.
+func StatModuleContext(ctx context.Context, name string, oldversion, newversion string, opts ...Option) ([]StatResult, error) {
+	cfg := newConfig(opts)
+
+	oldmod, err := GetModuleContext(ctx, name, oldversion)
 	if err != nil {
-		return nil, fmt.Errorf("running bench for old module: %v", err)
+		return nil, fmt.Errorf("getting old module: %w", err)
 	}
+	cfg.emit(Event{Kind: ModuleResolved, Side: OldSide, Module: oldmod})
 
-	newresults, err := benchModule(name, newversion)
+	newmod, err := GetModuleContext(ctx, name, newversion)
 	if err != nil {
-		return nil, fmt.Errorf("running bench for new module: %v", err)
+		return nil, fmt.Errorf("getting new module: %w", err)
 	}
+	cfg.emit(Event{Kind: ModuleResolved, Side: NewSide, Module: newmod})
 
-	return Stat(oldresults, newresults)
+	return StatModulesContext(ctx, oldmod, newmod, opts...)
+}
+
+// StatModules works like StatModule but on modules that were already
+// obtained, like the ones returned by GetModule or GitRevision.
+// The caller remains responsible for closing the given modules.
+//
+// The benchmarks can run with any Runner provided with WithRunner,
+// so the modules don't even need to be Go modules, like when
+// the runner replays recorded benchmark outputs.
+//
+// Any errors running "go" can be inspected in detail by
+// checking if the returned error is a CmdError. With RunOptions.Tolerant
+// the results of all packages that succeeded are returned along with
+// a *PartialError recording the failed packages, so benchmarks of
+// packages that failed on a single module are reported as added
+// or removed.
+func StatModules(oldmod, newmod Module, opts ...Option) ([]StatResult, error) {
+	return StatModulesContext(context.Background(), oldmod, newmod, opts...)
+}
+
+// StatModulesContext works like StatModules, but all commands are killed
+// if the context is done before they finish, returning an error that
+// wraps a *CanceledError.
+func StatModulesContext(ctx context.Context, oldmod, newmod Module, opts ...Option) ([]StatResult, error) {
+	cfg := newConfig(opts)
+	if err := cfg.run.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.stat.Validate(); err != nil {
+		return nil, err
+	}
+
+	runs, err := cfg.schedule.plan(cfg.run.withDefaultCount().Count)
+	if err != nil {
+		return nil, err
+	}
+
+	// By default test binaries are built only once per module and reused
+	// on all runs, avoiding compiling and linking tests on each run.
+	modules := map[Side]Module{OldSide: oldmod, NewSide: newmod}
+	runners := map[Side]Runner{OldSide: cfg.runner, NewSide: cfg.runner}
+
+	if cfg.runner == nil {
+		for _, side := range []Side{OldSide, NewSide} {
+			cfg.emit(Event{Kind: BuildStarted, Side: side, Module: modules[side]})
+			build, err := buildModule(ctx, modules[side], cfg.run)
+			if err != nil {
+				return nil, fmt.Errorf("building %s module: %w", side, err)
+			}
+			defer closeBuild(build)
+			runners[side] = build
+			cfg.emit(Event{
+				Kind:     BuildFinished,
+				Side:     side,
+				Module:   modules[side],
+				Packages: build.packages(),
+			})
+		}
+	}
+
+	results := map[Side]BenchResults{}
+	var failures []PackageFailure
+
+	for seq, run := range runs {
+		runopts := cfg.run
+		runopts.Count = run.count
+
+		event := Event{Side: run.side, Module: modules[run.side], Seq: seq, Runs: len(runs)}
+		event.Kind = RunStarted
+		cfg.emit(event)
+
+		res, err := runStream(ctx, runners[run.side], modules[run.side], runopts, func(result string) {
+			parsed := event
+			parsed.Kind = BenchParsed
+			parsed.Result = result
+			cfg.emit(parsed)
+		})
+		var partial *PartialError
+		if errors.As(err, &partial) {
+			failures = addFailures(failures, run.side, partial.Failures)
+		} else if err != nil {
+			return nil, fmt.Errorf("running bench for %s module: %w", run.side, err)
+		}
+		results[run.side] = append(results[run.side], res...)
+
+		event.Kind = RunFinished
+		event.Results = res
+		cfg.emit(event)
+
+		if cfg.onRun != nil {
+			cfg.onRun(BenchRun{
+				Seq:     seq,
+				Side:    run.side,
+				Results: res,
+			})
+		}
+	}
+
+	stats, err := stat(results[OldSide], results[NewSide], cfg.stat)
+	if err != nil {
+		return nil, err
+	}
+	cfg.emit(Event{Kind: StatsComputed, Stats: stats})
+
+	if len(failures) > 0 {
+		return stats, &PartialError{Failures: failures}
+	}
+	return stats, nil
 }
 
 // ParseChecker will parse the given string into a Check.
+// See CheckerFmt for details on the expected format.
 func ParseChecker(val string) (Checker, error) {
-	parsed := strings.Split(val, "=")
-	metric := parsed[0]
-	if len(parsed) != 2 {
+	check := val
+
+	var pattern *BenchRegexp
+	if sep := strings.LastIndex(check, ":"); sep != -1 {
+		var err error
+		pattern, err = CompileBenchRegexp(check[:sep])
+		if err != nil {
+			return Checker{}, fmt.Errorf("parsing checker %q benchmark regex: %v", val, err)
+		}
+		check = check[sep+1:]
+	}
+
+	sep := strings.IndexAny(check, "=<>")
+	if sep <= 0 {
 		return Checker{}, fmt.Errorf("checker on wrong format, expect: %q", CheckerFmt)
 	}
-	threshold, err := strconv.ParseFloat(strings.TrimSuffix(parsed[1], "%"), 64)
+	metric, limit := check[:sep], check[sep:]
+
+	checker := Checker{
+		repr:    val,
+		pattern: pattern,
+		metric:  metric,
+	}
+
+	for _, op := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(limit, op) {
+			checker.op = op
+			break
+		}
+	}
+	value := strings.TrimPrefix(limit, checker.op)
+	if checker.op != "=" {
+		checker.limit = limit
+	} else {
+		checker.limit = value
+	}
+
+	var err error
+	switch {
+	case checker.op != "=":
+		checker.kind = AbsoluteBound
+		checker.threshold, err = parseQuantity(metric, value)
+	case strings.HasSuffix(value, "%") || unitless(value):
+		checker.kind = PercentDelta
+		checker.threshold, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	default:
+		checker.kind = AbsoluteDelta
+		checker.threshold, err = parseQuantity(metric, value)
+	}
 	if err != nil {
-		return Checker{}, fmt.Errorf("parsing checker %q threshold", err)
+		return Checker{}, fmt.Errorf("parsing checker %q threshold: %v", val, err)
 	}
-	return Checker{
-		repr:      val,
-		metric:    metric,
-		threshold: threshold,
-	}, nil
+	return checker, nil
 }
 
-func newStatResults(tables []*benchstat.Table) []StatResult {
-	res := make([]StatResult, len(tables))
+func newStatResults(c *benchstat.Collection, deltaTest benchstat.DeltaTest) []StatResult {
+	tables := map[string]*benchstat.Table{}
+	for _, table := range c.Tables() {
+		tables[table.Metric] = table
+	}
+
+	res := []StatResult{}
+	for _, unit := range c.Units {
+		metric := metricOf(unit)
+		added := missingBenchmarks(c, unit, "old")
+		removed := missingBenchmarks(c, unit, "new")
 
-	for i, table := range tables {
-		res[i] = StatResult{
-			Metric:     table.Metric,
-			BenchDiffs: newBenchResults(table.Rows),
+		// benchstat omits benchmarks that are not on both old and new
+		// results, omitting the whole metric if there is none left.
+		table, ok := tables[metric]
+		if !ok && len(added) == 0 && len(removed) == 0 {
+			continue
 		}
+		var rows []*benchstat.Row
+		if ok {
+			rows = table.Rows
+		}
+		res = append(res, StatResult{
+			Metric:     metric,
+			BenchDiffs: newBenchResults(c, rows, deltaTest),
+			Added:      added,
+			Removed:    removed,
+		})
 	}
 
 	return res
 }
 
-func newBenchResults(rows []*benchstat.Row) []BenchDiff {
+// missingBenchmarks returns the benchmarks with results on the given
+// unit that are missing on the given config, in order of appearance.
+func missingBenchmarks(c *benchstat.Collection, unit string, config string) []BenchName {
+	var missing []BenchName
+	for _, group := range c.Groups {
+		for _, bench := range c.Benchmarks[group] {
+			key := benchstat.Key{Group: group, Benchmark: bench, Unit: unit}
+			found := false
+			for _, key.Config = range c.Configs {
+				if c.Metrics[key] != nil {
+					found = true
+					break
+				}
+			}
+			key.Config = config
+			if found && c.Metrics[key] == nil {
+				missing = append(missing, BenchName{Package: group, Name: bench})
+			}
+		}
+	}
+	return missing
+}
+
+// metricOf returns the name of the metric with the given unit,
+// same as benchstat does for the tables.
+func metricOf(unit string) string {
+	metrics := map[string]string{
+		"ns/op": TimeMetric,
+		"ns/GC": "time/GC",
+		"B/op":  AllocMetric,
+		"MB/s":  SpeedMetric,
+	}
+	if metric, ok := metrics[unit]; ok {
+		return metric
+	}
+	for suffix, metric := range metrics {
+		if strings.HasSuffix(unit, "-"+suffix) {
+			return strings.TrimSuffix(unit, "-"+suffix) + "-" + metric
+		}
+	}
+	return unit
+}
+
+func newBenchResults(c *benchstat.Collection, rows []*benchstat.Row, deltaTest benchstat.DeltaTest) []BenchDiff {
 	res := make([]BenchDiff, len(rows))
 
 	for i, row := range rows {
@@ -267,39 +1009,146 @@ func newBenchResults(rows []*benchstat.Row) []BenchDiff {
 			panic(fmt.Errorf("should always have 2 metrics, instead got: %d", len(row.Metrics)))
 		}
 
+		oldm, newm := row.Metrics[0], row.Metrics[1]
+
+		// benchstat only provides the p-value formatted inside
+		// the row note, so we run the same test again.
+		// The significance follows the same rules benchstat uses
+		// to decide if the delta is reported or not.
+		pval, err := deltaTest(oldm, newm)
+		significant := err == nil && pval < c.Alpha
+		if err != nil {
+			pval = -1
+		}
+
 		res[i] = BenchDiff{
-			Name:  row.Benchmark,
-			Old:   row.Metrics[0].Format(row.Scaler),
-			New:   row.Metrics[1].Format(row.Scaler),
-			Delta: row.PctDelta,
+			Name:        row.Benchmark,
+			Package:     rowPackage(c, row),
+			Old:         oldm.Format(row.Scaler),
+			New:         newm.Format(row.Scaler),
+			Delta:       row.PctDelta,
+			Unit:        oldm.Unit,
+			OldStats:    newBenchStats(oldm),
+			NewStats:    newBenchStats(newm),
+			PValue:      pval,
+			Significant: significant,
 		}
 	}
 
 	return res
 }
 
+// rowPackage returns the package of the benchmarks of the row.
+// benchstat only sets the group of rows when there is more than one,
+// and the geometric mean row has no group since it is computed
+// from all benchmarks.
+func rowPackage(c *benchstat.Collection, row *benchstat.Row) string {
+	if row.Group != "" || len(c.Groups) != 1 || row.Benchmark == geoMeanRow {
+		return row.Group
+	}
+	return c.Groups[0]
+}
+
+func newBenchStats(m *benchstat.Metrics) BenchStats {
+	stats := BenchStats{
+		Mean:    m.Mean,
+		Min:     m.Min,
+		Max:     m.Max,
+		Samples: len(m.RValues),
+	}
+
+	if len(m.RValues) > 0 {
+		values := append([]float64(nil), m.RValues...)
+		sort.Float64s(values)
+		middle := len(values) / 2
+		stats.Median = values[middle]
+		if len(values)%2 == 0 {
+			stats.Median = (values[middle-1] + values[middle]) / 2
+		}
+	}
+
+	// Same as benchstat.Metrics.FormatDiff.
+	if m.Mean != 0 && m.Max != 0 {
+		stats.Variation = math.Max(1-m.Min/m.Mean, m.Max/m.Mean-1) * 100
+	}
+
+	return stats
+}
+
+func closeBuild(build moduleBuild) {
+	// Failing to remove temporary build files doesn't affect results.
+	_ = build.Close()
+}
+
 func resultsReader(res BenchResults) io.Reader {
 	return strings.NewReader(strings.Join(res, "\n"))
 }
 
-func benchModule(name string, version string) (BenchResults, error) {
-	mod, err := GetModule(name, version)
-	if err != nil {
-		return nil, err
+// addResults parses the results as records of the Go benchmark format
+// and adds them to the collection on the given config. Benchmarks are
+// grouped by their package, so benchmarks with the same name on
+// different packages are compared separately.
+func addResults(c *benchstat.Collection, config string, res BenchResults) error {
+	addString(&c.Configs, config)
+
+	reader := benchfmt.NewReader(resultsReader(res), config)
+	for reader.Scan() {
+		// Lines that don't parse, like on a panicking benchmark,
+		// are ignored just like benchstat does.
+		result, ok := reader.Result().(*benchfmt.Result)
+		if !ok {
+			continue
+		}
+		key := benchstat.Key{
+			Config:    config,
+			Group:     result.GetConfig("pkg"),
+			Benchmark: result.Name.String(),
+		}
+		for _, value := range result.Values {
+			// benchfmt tidies units, like ns/op to sec/op, but results
+			// keep the units of go test, like benchstat does.
+			key.Unit = value.Unit
+			val := value.Value
+			if value.OrigUnit != "" {
+				key.Unit, val = value.OrigUnit, value.OrigValue
+			}
+			m := addMetrics(c, key)
+			m.Values = append(m.Values, val)
+		}
 	}
-	// benchstat requires multiple runs of the same benchmarks
-	// so it can assess statistically for abnormalities, etc.
-	const benchruns = 5
+	return reader.Err()
+}
 
-	results := BenchResults{}
+// addMetrics returns the metrics of the key on the collection,
+// adding them if needed, same as benchstat does when adding results.
+func addMetrics(c *benchstat.Collection, key benchstat.Key) *benchstat.Metrics {
+	if c.Metrics == nil {
+		c.Metrics = map[benchstat.Key]*benchstat.Metrics{}
+	}
+	if m, ok := c.Metrics[key]; ok {
+		return m
+	}
 
-	for i := 0; i < benchruns; i++ {
-		res, err := RunBench(mod)
-		if err != nil {
-			return nil, err
-		}
-		results = append(results, res...)
+	addString(&c.Configs, key.Config)
+	addString(&c.Groups, key.Group)
+	if c.Benchmarks == nil {
+		c.Benchmarks = map[string][]string{}
 	}
+	benchmarks := c.Benchmarks[key.Group]
+	addString(&benchmarks, key.Benchmark)
+	c.Benchmarks[key.Group] = benchmarks
+	addString(&c.Units, key.Unit)
 
-	return results, nil
+	m := &benchstat.Metrics{Unit: key.Unit}
+	c.Metrics[key] = m
+	return m
+}
+
+func addString(strs *[]string, str string) {
+	for _, s := range *strs {
+		if s == str {
+			return
+		}
+	}
+	*strs = append(*strs, str)
 }
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/madlambda/benchcheck"
@@ -128,6 +129,30 @@ func TestGetModule(t *testing.T) {
 	}
 }
 
+func TestLocalModule(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "file"), "uncommitted")
+
+	mod, err := benchcheck.LocalModule(dir)
+	assertNoError(t, err, "benchcheck.LocalModule(%q)", dir)
+
+	if !filepath.IsAbs(mod.Path()) {
+		t.Fatalf("want absolute module path, got %q", mod.Path())
+	}
+	data, err := os.ReadFile(filepath.Join(mod.Path(), "file"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "uncommitted", string(data))
+	assert.NoError(t, mod.Close())
+
+	_, err = benchcheck.LocalModule(filepath.Join(dir, "file"))
+	assert.Error(t, err, "want error for module on regular file")
+
+	_, err = benchcheck.LocalModule(filepath.Join(dir, "nonexistent"))
+	assert.Error(t, err, "want error for nonexistent module dir")
+}
+
 func TestChecker(t *testing.T) {
 	t.Parallel()
 
@@ -280,6 +305,62 @@ func TestChecker(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name:     "parse fails on invalid benchmark regex",
+			check:    "Benchmark(:metric=+20%",
+			parseErr: true,
+		},
+		{
+			name:     "parse fails on missing metric",
+			check:    "BenchmarkEncode.*:=+20%",
+			parseErr: true,
+		},
+		{
+			name:  "stat check fails on matching benchmark",
+			check: "BenchmarkEncode.*:metric=+2%",
+			stat: benchcheck.StatResult{
+				Metric: "metric",
+				BenchDiffs: []benchcheck.BenchDiff{
+					{Name: "EncodeJSON-8", Delta: 3.0},
+				},
+			},
+			want: false,
+		},
+		{
+			name:  "stat check pass on benchmarks not matching",
+			check: "BenchmarkEncode.*:metric=+2%",
+			stat: benchcheck.StatResult{
+				Metric: "metric",
+				BenchDiffs: []benchcheck.BenchDiff{
+					{Name: "Setup-8", Delta: 15.0},
+					{Name: "EncodeJSON-8", Delta: 1.0},
+				},
+			},
+			want: true,
+		},
+		{
+			name:  "stat check with anchored benchmark regex",
+			check: "^BenchmarkEncode$:metric=+2%",
+			stat: benchcheck.StatResult{
+				Metric: "metric",
+				BenchDiffs: []benchcheck.BenchDiff{
+					{Name: "EncodeJSON", Delta: 15.0},
+					{Name: "Encode", Delta: 3.0},
+				},
+			},
+			want: false,
+		},
+		{
+			name:  "stat check with benchmark regex containing colons",
+			check: "Benchmark(?:Gob|JSON)Encode:metric=+2%",
+			stat: benchcheck.StatResult{
+				Metric: "metric",
+				BenchDiffs: []benchcheck.BenchDiff{
+					{Name: "GobEncode", Delta: 3.0},
+				},
+			},
+			want: false,
+		},
 	}
 
 	for _, tc := range tcases {
@@ -302,42 +383,644 @@ func TestChecker(t *testing.T) {
 	}
 }
 
+func TestCheckerEvaluate(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name  string
+		check string
+		stat  benchcheck.StatResult
+		want  []benchcheck.Violation
+	}
+
+	diffs := []benchcheck.BenchDiff{
+		{Name: "Same", Old: "1.00ms", New: "1.00ms", Delta: 0.0},
+		{Name: "Slower", Old: "1.00ms", New: "1.30ms", Delta: 30.0},
+		{Name: "MuchSlower", Old: "1.00ms", New: "2.00ms", Delta: 100.0},
+		{Name: "Faster", Old: "1.00ms", New: "0.70ms", Delta: -30.0},
+	}
+
+	tcases := []testcase{
+		{
+			name:  "no violations on unknown metric",
+			check: "metric=+20%",
+			stat:  benchcheck.StatResult{Metric: "other", BenchDiffs: diffs},
+		},
+		{
+			name:  "no violations",
+			check: "metric=+200%",
+			stat:  benchcheck.StatResult{Metric: "metric", BenchDiffs: diffs},
+		},
+		{
+			name:  "all positive violations",
+			check: "metric=+20%",
+			stat:  benchcheck.StatResult{Metric: "metric", BenchDiffs: diffs},
+			want: []benchcheck.Violation{
+				{BenchDiff: diffs[1], Threshold: 20.0, Limit: "+20%", Kind: benchcheck.PercentDelta},
+				{BenchDiff: diffs[2], Threshold: 20.0, Limit: "+20%", Kind: benchcheck.PercentDelta},
+			},
+		},
+		{
+			name:  "all negative violations",
+			check: "metric=-20%",
+			stat:  benchcheck.StatResult{Metric: "metric", BenchDiffs: diffs},
+			want: []benchcheck.Violation{
+				{BenchDiff: diffs[3], Threshold: -20.0, Limit: "-20%", Kind: benchcheck.PercentDelta},
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			check, err := benchcheck.ParseChecker(tcase.check)
+			assert.NoError(t, err)
+
+			report := check.Evaluate(tcase.stat)
+
+			assert.EqualStrings(t, tcase.stat.Metric, report.Metric)
+			assert.EqualStrings(t, check.String(), report.Checker.String())
+			assertEqualWithFloat(t, report.Violations, tcase.want)
+
+			if report.Passed() != (len(tcase.want) == 0) {
+				t.Fatalf("report.Passed()=%t with violations: %v", report.Passed(), report.Violations)
+			}
+			if report.Passed() != check.Do(tcase.stat) {
+				t.Fatalf("report.Passed()=%t != check.Do()=%t", report.Passed(), check.Do(tcase.stat))
+			}
+			for _, v := range report.Violations {
+				if !strings.Contains(report.String(), v.String()) {
+					t.Fatalf("report %q should contain violation %q", report, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckerAbsoluteThresholds(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name     string
+		check    string
+		stat     benchcheck.StatResult
+		want     bool
+		parseErr bool
+	}
+
+	diff := func(metric string, oldmean, newmean, delta float64) benchcheck.StatResult {
+		return benchcheck.StatResult{
+			Metric: metric,
+			BenchDiffs: []benchcheck.BenchDiff{
+				{
+					Name:        "Bench",
+					Delta:       delta,
+					OldStats:    benchcheck.BenchStats{Mean: oldmean, Samples: 5},
+					NewStats:    benchcheck.BenchStats{Mean: newmean, Samples: 5},
+					Significant: delta != 0,
+				},
+			},
+		}
+	}
+
+	tcases := []testcase{
+		{
+			name:     "parse fails on unit of other metric",
+			check:    "time/op<1KB",
+			parseErr: true,
+		},
+		{
+			name:     "parse fails on unknown unit",
+			check:    "alloc/op<=1 parsec",
+			parseErr: true,
+		},
+		{
+			name:     "parse fails on absolute delta with wrong unit",
+			check:    "allocs/op=+1ms",
+			parseErr: true,
+		},
+		{
+			name:     "parse fails on missing bound",
+			check:    "allocs/op<=",
+			parseErr: true,
+		},
+		{
+			name:     "parse fails on absolute delta with unit of other metric",
+			check:    "alloc/op=+1allocs",
+			parseErr: true,
+		},
+		{
+			name:     "parse fails on missing metric",
+			check:    "<=2",
+			parseErr: true,
+		},
+		{
+			name:  "allocs bound passes",
+			check: "allocs/op<=2",
+			stat:  diff(benchcheck.AllocsMetric, 1, 2, 100),
+			want:  true,
+		},
+		{
+			name:  "allocs bound fails",
+			check: "allocs/op<=2",
+			stat:  diff(benchcheck.AllocsMetric, 2, 3, 50),
+			want:  false,
+		},
+		{
+			name:  "bounds ignore significance",
+			check: "allocs/op<3",
+			stat:  diff(benchcheck.AllocsMetric, 3, 3, 0),
+			want:  false,
+		},
+		{
+			name:  "time bound in ms passes",
+			check: "time/op<1ms",
+			stat:  diff(benchcheck.TimeMetric, 9e5, 999999, 11.1),
+			want:  true,
+		},
+		{
+			name:  "time bound in ms fails",
+			check: "time/op<1ms",
+			stat:  diff(benchcheck.TimeMetric, 9e5, 1e6, 11.1),
+			want:  false,
+		},
+		{
+			name:  "time bound in µs",
+			check: "time/op<=1.5µs",
+			stat:  diff(benchcheck.TimeMetric, 1000, 1600, 60),
+			want:  false,
+		},
+		{
+			name:  "time bound in us",
+			check: "time/op<=1.5us",
+			stat:  diff(benchcheck.TimeMetric, 1000, 1400, 40),
+			want:  true,
+		},
+		{
+			name:  "alloc bound in KB",
+			check: "alloc/op<2KB",
+			stat:  diff(benchcheck.AllocMetric, 1000, 2000, 100),
+			want:  false,
+		},
+		{
+			name:  "speed lower bound passes",
+			check: "speed>=50MB/s",
+			stat:  diff(benchcheck.SpeedMetric, 60, 50, -16.6),
+			want:  true,
+		},
+		{
+			name:  "speed lower bound fails",
+			check: "speed>0.1GB/s",
+			stat:  diff(benchcheck.SpeedMetric, 60, 50, -16.6),
+			want:  false,
+		},
+		{
+			name:  "absolute delta passes",
+			check: "allocs/op=+1allocs",
+			stat:  diff(benchcheck.AllocsMetric, 2, 3, 50),
+			want:  true,
+		},
+		{
+			name:  "absolute delta fails",
+			check: "allocs/op=+1allocs",
+			stat:  diff(benchcheck.AllocsMetric, 2, 4, 100),
+			want:  false,
+		},
+		{
+			name:  "absolute negative delta fails",
+			check: "time/op=-1ms",
+			stat:  diff(benchcheck.TimeMetric, 3e6, 1.5e6, -50),
+			want:  false,
+		},
+		{
+			name:  "absolute delta ignores non significant",
+			check: "time/op=+1ms",
+			stat:  diff(benchcheck.TimeMetric, 3e6, 5e6, 0),
+			want:  true,
+		},
+		{
+			name:  "alloc delta in B",
+			check: "alloc/op=+100B",
+			stat:  diff(benchcheck.AllocMetric, 1000, 1150, 15),
+			want:  false,
+		},
+		{
+			name:  "time delta in ns",
+			check: "time/op=+10ns",
+			stat:  diff(benchcheck.TimeMetric, 100, 109, 9),
+			want:  true,
+		},
+		{
+			name:  "delta with no unit on allocs is percent",
+			check: "allocs/op=5",
+			stat:  diff(benchcheck.AllocsMetric, 20, 21, 5),
+			want:  true,
+		},
+		{
+			name:  "delta with no unit on alloc is percent",
+			check: "alloc/op=+100",
+			stat:  diff(benchcheck.AllocMetric, 1000, 1900, 90),
+			want:  true,
+		},
+		{
+			name:  "delta with no unit on time is percent",
+			check: "time/op=10",
+			stat:  diff(benchcheck.TimeMetric, 100, 115, 15),
+			want:  false,
+		},
+		{
+			name:  "negative delta with no unit on speed is percent",
+			check: "speed=-10",
+			stat:  diff(benchcheck.SpeedMetric, 100, 95, -5),
+			want:  true,
+		},
+		{
+			name:  "absolute delta with SI prefix on unknown metric",
+			check: "widgets/op=+1k",
+			stat:  diff("widgets/op", 1000, 2500, 150),
+			want:  false,
+		},
+		{
+			name:  "percent delta on unknown metric",
+			check: "widgets/op=+5%",
+			stat:  diff("widgets/op", 100, 110, 10),
+			want:  false,
+		},
+		{
+			name:  "percent delta on known metric",
+			check: "allocs/op=+40%",
+			stat:  diff(benchcheck.AllocsMetric, 2, 3, 50),
+			want:  false,
+		},
+		{
+			name:  "absolute bound with benchmark regex",
+			check: "BenchmarkOther:allocs/op<=2",
+			stat:  diff(benchcheck.AllocsMetric, 2, 3, 50),
+			want:  true,
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			check, err := benchcheck.ParseChecker(tcase.check)
+			if tcase.parseErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			report := check.Evaluate(tcase.stat)
+			if report.Passed() != tcase.want {
+				t.Fatalf("check %q on %v: passed=%t; want %t", tcase.check, tcase.stat, report.Passed(), tcase.want)
+			}
+			for _, v := range report.Violations {
+				if v.Kind != benchcheck.PercentDelta && !strings.Contains(v.String(), v.Limit) {
+					t.Fatalf("violation %q should contain limit %q", v, v.Limit)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckerRequireSignificance(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name             string
+		check            string
+		failInconclusive bool
+		wantViolations   []string
+		wantInconclusive []string
+		wantStatus       benchcheck.CheckStatus
+	}
+
+	stats := func(mean float64) benchcheck.BenchStats {
+		return benchcheck.BenchStats{Mean: mean, Samples: 5}
+	}
+	stat := benchcheck.StatResult{
+		Metric: "metric",
+		BenchDiffs: []benchcheck.BenchDiff{
+			{
+				Name:        "Same",
+				OldStats:    stats(100),
+				NewStats:    stats(100),
+				PValue:      1,
+				Significant: false,
+			},
+			{
+				Name:        "SignificantlySlower",
+				Delta:       30.0,
+				OldStats:    stats(100),
+				NewStats:    stats(130),
+				PValue:      0.01,
+				Significant: true,
+			},
+			{
+				Name:        "NoisySlower",
+				OldStats:    stats(100),
+				NewStats:    stats(150),
+				PValue:      0.3,
+				Significant: false,
+			},
+			{
+				Name:        "TooFewSamples",
+				OldStats:    stats(100),
+				NewStats:    stats(110),
+				PValue:      -1,
+				Significant: false,
+			},
+		},
+	}
+
+	tcases := []testcase{
+		{
+			name:       "passes",
+			check:      "metric=+60%",
+			wantStatus: benchcheck.CheckPassed,
+		},
+		{
+			name:             "inconclusive",
+			check:            "metric=+40%",
+			wantInconclusive: []string{"NoisySlower"},
+			wantStatus:       benchcheck.CheckInconclusive,
+		},
+		{
+			name:             "fail inconclusive",
+			check:            "metric=+40%",
+			failInconclusive: true,
+			wantInconclusive: []string{"NoisySlower"},
+			wantStatus:       benchcheck.CheckFailed,
+		},
+		{
+			name:             "violations and inconclusive",
+			check:            "metric=+5%",
+			wantViolations:   []string{"SignificantlySlower"},
+			wantInconclusive: []string{"NoisySlower", "TooFewSamples"},
+			wantStatus:       benchcheck.CheckFailed,
+		},
+	}
+
+	names := func(violations []benchcheck.Violation) []string {
+		var names []string
+		for _, v := range violations {
+			names = append(names, v.Name)
+		}
+		return names
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			check, err := benchcheck.ParseChecker(tcase.check)
+			assert.NoError(t, err)
+
+			withoutSignificance := check.Evaluate(stat)
+			if !withoutSignificance.Passed() && tcase.wantViolations == nil {
+				t.Fatalf("checker without significance should ignore non significant deltas: %v", withoutSignificance)
+			}
+
+			check = check.RequireSignificance(tcase.failInconclusive)
+			report := check.Evaluate(stat)
+
+			if diff := cmp.Diff(tcase.wantViolations, names(report.Violations)); diff != "" {
+				t.Fatalf("violations: %s", diff)
+			}
+			if diff := cmp.Diff(tcase.wantInconclusive, names(report.Inconclusive)); diff != "" {
+				t.Fatalf("inconclusive: %s", diff)
+			}
+			if got := report.Status(); got != tcase.wantStatus {
+				t.Fatalf("report.Status()=%q; want %q", got, tcase.wantStatus)
+			}
+			if report.Passed() != (tcase.wantStatus != benchcheck.CheckFailed) {
+				t.Fatalf("report.Passed()=%t with status %q", report.Passed(), report.Status())
+			}
+			for _, v := range report.Inconclusive {
+				if !strings.Contains(report.String(), v.String()) {
+					t.Fatalf("report %q should contain inconclusive %q", report, v)
+				}
+				if !strings.Contains(v.String(), "not significant") {
+					t.Fatalf("inconclusive %q should mention it is not significant", v)
+				}
+			}
+		})
+	}
+}
+
 func TestBenchModule(t *testing.T) {
 	t.Parallel()
 
-	const (
-		module     = "github.com/madlambda/benchcheck"
-		modversion = "73348d58a038746fd4f92dd1e77344a58a4f8505"
-	)
-	mod, err := benchcheck.GetModule(module, modversion)
-	assertNoError(t, err, "benchcheck.GetModule(%q, %q)", module, modversion)
+	mod := benchModule(t, map[string]string{
+		"fake_test.go": `package bench
 
-	res, err := benchcheck.RunBench(mod)
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkFake(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		time.Sleep(time.Millisecond)
+	}
+}
+`,
+	})
+
+	res, err := benchcheck.RunBench(mod, benchcheck.WithRunOptions(benchcheck.RunOptions{BenchTime: "10x"}))
 	assertNoError(t, err, "benchcheck.RunBench(%v)", mod)
 
-	assert.EqualInts(t, 1, len(res), "want single result, got: %v", res)
-	if !strings.HasPrefix(res[0], "BenchmarkFake") {
-		t.Fatalf("bench result has wrong prefix: %s", res[0])
+	results := benchmarkResults(res)
+	assert.EqualInts(t, 1, len(results), "want single result, got: %v", res)
+	if !strings.HasPrefix(results[0], "BenchmarkFake") {
+		t.Fatalf("bench result has wrong prefix: %s", results[0])
 	}
-	if !strings.Contains(res[0], "ns/op") {
-		t.Fatalf("bench result should contain time info: %s", res[0])
+	if !strings.Contains(results[0], "ns/op") {
+		t.Fatalf("bench result should contain time info: %s", results[0])
 	}
 }
 
 func TestBenchModuleNoBenchmarks(t *testing.T) {
 	t.Parallel()
 
-	const (
-		module     = "github.com/madlambda/benchcheck"
-		modversion = "f15923bf230cc7331ad869fcdaac35172f8b7f38"
-	)
-	mod, err := benchcheck.GetModule(module, modversion)
-	assertNoError(t, err, "benchcheck.GetModule(%q, %q)", module, modversion)
+	mod := benchModule(t, map[string]string{
+		"fake.go": "package bench\n",
+		"fake_test.go": `package bench
+
+import "testing"
+
+func TestFake(t *testing.T) {}
+`,
+	})
 
 	res, err := benchcheck.RunBench(mod)
 	assertNoError(t, err, "benchcheck.RunBench(%v)", mod)
 
-	assert.EqualInts(t, 0, len(res), "want no results, got: %v", res)
+	assert.EqualInts(t, 0, len(benchmarkResults(res)), "want no results, got: %v", res)
+}
+
+func TestRunBenchOptions(t *testing.T) {
+	t.Parallel()
+
+	mod := benchModule(t, map[string]string{
+		"a/a_test.go": `package a
+
+import "testing"
+
+func BenchmarkA(b *testing.B)  {}
+func BenchmarkA2(b *testing.B) {}
+`,
+		"b/b_test.go": `package b
+
+import "testing"
+
+func BenchmarkB(b *testing.B) {}
+func TestFail(t *testing.T)   { t.Fatal("tests should not run") }
+`,
+		"tagged/tagged_test.go": `//go:build custom
+// +build custom
+
+package tagged
+
+import "testing"
+
+func BenchmarkTagged(b *testing.B) {}
+`,
+	})
+
+	type testcase struct {
+		name    string
+		opts    benchcheck.RunOptions
+		want    []string
+		wantErr bool
+	}
+
+	tcases := []testcase{
+		{
+			name: "defaults run all benchmarks and no tests",
+			want: []string{"BenchmarkA", "BenchmarkA2", "BenchmarkB"},
+		},
+		{
+			name: "bench regex",
+			opts: benchcheck.RunOptions{Bench: "A2$"},
+			want: []string{"BenchmarkA2"},
+		},
+		{
+			name: "packages",
+			opts: benchcheck.RunOptions{Packages: []string{"./b"}},
+			want: []string{"BenchmarkB"},
+		},
+		{
+			name: "tags",
+			opts: benchcheck.RunOptions{
+				Bench:    "Tagged",
+				Packages: []string{"./tagged"},
+				Tags:     []string{"custom"},
+			},
+			want: []string{"BenchmarkTagged"},
+		},
+		{
+			name: "count and benchtime",
+			opts: benchcheck.RunOptions{
+				Packages:  []string{"./b"},
+				Count:     3,
+				BenchTime: "10x",
+			},
+			want: []string{"BenchmarkB", "BenchmarkB", "BenchmarkB"},
+		},
+		{
+			name: "cpu list",
+			opts: benchcheck.RunOptions{
+				Packages: []string{"./b"},
+				CPU:      []int{1, 2},
+				Timeout:  time.Minute,
+			},
+			want: []string{"BenchmarkB", "BenchmarkB"},
+		},
+		{
+			name: "run tests",
+			opts: benchcheck.RunOptions{
+				Run:      "Fail",
+				Packages: []string{"./b"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid options",
+			opts:    benchcheck.RunOptions{BenchTime: "10"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := benchcheck.RunBench(mod, benchcheck.WithRunOptions(tcase.opts))
+			if tcase.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assertNoError(t, err, "benchcheck.RunBench(%v, %v)", mod, tcase.opts)
+
+			results := benchmarkResults(res)
+			got := make([]string, len(results))
+			for i, r := range results {
+				got[i] = stripProcCount(strings.Fields(r)[0])
+			}
+			if diff := cmp.Diff(tcase.want, got); diff != "" {
+				t.Fatalf("got benchmarks %v, want %v: %s", got, tcase.want, diff)
+			}
+		})
+	}
+}
+
+func TestRunBenchMem(t *testing.T) {
+	t.Parallel()
+
+	mod := benchModule(t, map[string]string{
+		"bench_test.go": `package bench
+
+import "testing"
+
+var sink []byte
+
+func BenchmarkAlloc(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		sink = make([]byte, 64)
+	}
+}
+`,
+	})
+
+	res, err := benchcheck.RunBench(mod, benchcheck.WithRunOptions(benchcheck.RunOptions{BenchTime: "10x"}))
+	assertNoError(t, err)
+	results := benchmarkResults(res)
+	assert.EqualInts(t, 1, len(results), "want single result, got: %v", res)
+
+	for _, unit := range []string{"ns/op", "B/op", "allocs/op"} {
+		if !strings.Contains(results[0], unit) {
+			t.Fatalf("bench result should contain %q by default: %s", unit, results[0])
+		}
+	}
+
+	res, err = benchcheck.RunBench(mod, benchcheck.WithRunOptions(benchcheck.RunOptions{
+		BenchTime:  "10x",
+		NoBenchMem: true,
+	}))
+	assertNoError(t, err)
+	results = benchmarkResults(res)
+	assert.EqualInts(t, 1, len(results), "want single result, got: %v", res)
+
+	if strings.Contains(results[0], "B/op") {
+		t.Fatalf("bench result should have no memory stats: %s", results[0])
+	}
 }
 
 func TestStatBenchmarkResults(t *testing.T) {
@@ -384,12 +1067,49 @@ func TestStatBenchmarkResults(t *testing.T) {
 							Delta: -13.3,
 							Old:   "13.6ms ± 1%",
 							New:   "11.8ms ± 1%",
+							Unit:  "ns/op",
+							OldStats: benchcheck.BenchStats{
+								Mean:      1.3599058e+07,
+								Median:    1.35801495e+07,
+								Min:       1.3552735e+07,
+								Max:       1.3683198e+07,
+								Variation: 0.6187,
+								Samples:   4,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      1.17892886e+07,
+								Median:    1.1786159e+07,
+								Min:       1.1628583e+07,
+								Max:       1.1942588e+07,
+								Variation: 1.3631,
+								Samples:   5,
+							},
+							PValue:      0.0158,
+							Significant: true,
 						},
 						{
 							Name:  "JSONEncode",
 							Delta: 0.0,
 							Old:   "32.1ms ± 1%",
 							New:   "31.8ms ± 1%",
+							Unit:  "ns/op",
+							OldStats: benchcheck.BenchStats{
+								Mean:      3.21142985e+07,
+								Median:    3.21635525e+07,
+								Min:       3.1735022e+07,
+								Max:       3.2395067e+07,
+								Variation: 1.1810,
+								Samples:   4,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      3.17613552e+07,
+								Median:    3.1765634e+07,
+								Min:       3.1288355e+07,
+								Max:       3.2156552e+07,
+								Variation: 1.4892,
+								Samples:   5,
+							},
+							PValue: 0.2857,
 						},
 					},
 				},
@@ -401,19 +1121,164 @@ func TestStatBenchmarkResults(t *testing.T) {
 							Delta: 15.35,
 							Old:   "56.4MB/s ± 1%",
 							New:   "65.1MB/s ± 1%",
+							Unit:  "MB/s",
+							OldStats: benchcheck.BenchStats{
+								Mean:      56.44,
+								Median:    56.52,
+								Min:       56.09,
+								Max:       56.63,
+								Variation: 0.6201,
+								Samples:   4,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      65.108,
+								Median:    65.12,
+								Min:       64.27,
+								Max:       66,
+								Variation: 1.3700,
+								Samples:   5,
+							},
+							PValue:      0.0158,
+							Significant: true,
 						},
 						{
 							Name:  "JSONEncode",
 							Delta: 0.0,
 							Old:   "60.4MB/s ± 1%",
 							New:   "61.1MB/s ± 2%",
+							Unit:  "MB/s",
+							OldStats: benchcheck.BenchStats{
+								Mean:      60.4275,
+								Median:    60.33,
+								Min:       59.9,
+								Max:       61.15,
+								Variation: 1.1956,
+								Samples:   4,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      61.102,
+								Median:    61.09,
+								Min:       60.34,
+								Max:       62.02,
+								Variation: 1.5024,
+								Samples:   5,
+							},
+							PValue: 0.2857,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "memory allocation metrics",
+			oldres: []string{
+				"BenchmarkAlloc-8   	1000	  1000 ns/op	  64 B/op	  2 allocs/op",
+				"BenchmarkAlloc-8   	1000	  1001 ns/op	  64 B/op	  2 allocs/op",
+				"BenchmarkAlloc-8   	1000	  1002 ns/op	  64 B/op	  2 allocs/op",
+				"BenchmarkAlloc-8   	1000	  1003 ns/op	  64 B/op	  2 allocs/op",
+				"BenchmarkAlloc-8   	1000	  1004 ns/op	  64 B/op	  2 allocs/op",
+			},
+			newres: []string{
+				"BenchmarkAlloc-8   	1000	  1000 ns/op	  128 B/op	  3 allocs/op",
+				"BenchmarkAlloc-8   	1000	  1001 ns/op	  128 B/op	  3 allocs/op",
+				"BenchmarkAlloc-8   	1000	  1002 ns/op	  128 B/op	  3 allocs/op",
+				"BenchmarkAlloc-8   	1000	  1003 ns/op	  128 B/op	  3 allocs/op",
+				"BenchmarkAlloc-8   	1000	  1004 ns/op	  128 B/op	  3 allocs/op",
+			},
+			want: []benchcheck.StatResult{
+				{
+					Metric: benchcheck.TimeMetric,
+					BenchDiffs: []benchcheck.BenchDiff{
+						{
+							Name:  "Alloc-8",
+							Delta: 0.0,
+							Old:   "1.00µs ± 0%",
+							New:   "1.00µs ± 0%",
+							Unit:  "ns/op",
+							OldStats: benchcheck.BenchStats{
+								Mean:      1002,
+								Median:    1002,
+								Min:       1000,
+								Max:       1004,
+								Variation: 0.1996,
+								Samples:   5,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      1002,
+								Median:    1002,
+								Min:       1000,
+								Max:       1004,
+								Variation: 0.1996,
+								Samples:   5,
+							},
+							PValue: 1,
+						},
+					},
+				},
+				{
+					Metric: benchcheck.AllocMetric,
+					BenchDiffs: []benchcheck.BenchDiff{
+						{
+							Name:  "Alloc-8",
+							Delta: 100.0,
+							Old:   "64.0B ± 0%",
+							New:   "128.0B ± 0%",
+							Unit:  "B/op",
+							OldStats: benchcheck.BenchStats{
+								Mean:      64,
+								Median:    64,
+								Min:       64,
+								Max:       64,
+								Variation: 0,
+								Samples:   5,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      128,
+								Median:    128,
+								Min:       128,
+								Max:       128,
+								Variation: 0,
+								Samples:   5,
+							},
+							PValue:      0.0079,
+							Significant: true,
+						},
+					},
+				},
+				{
+					Metric: benchcheck.AllocsMetric,
+					BenchDiffs: []benchcheck.BenchDiff{
+						{
+							Name:  "Alloc-8",
+							Delta: 50.0,
+							Old:   "2.00 ± 0%",
+							New:   "3.00 ± 0%",
+							Unit:  "allocs/op",
+							OldStats: benchcheck.BenchStats{
+								Mean:      2,
+								Median:    2,
+								Min:       2,
+								Max:       2,
+								Variation: 0,
+								Samples:   5,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      3,
+								Median:    3,
+								Min:       3,
+								Max:       3,
+								Variation: 0,
+								Samples:   5,
+							},
+							PValue:      0.0079,
+							Significant: true,
 						},
 					},
 				},
 			},
 		},
 		{
-			name: "benchmarks not present on both old/new are ignored",
+			name: "benchmarks not present on both old/new are added/removed",
 			oldres: []string{
 				"BenchmarkGobEncode   	100	  13552735 ns/op	  56.63 MB/s",
 				"BenchmarkJSONEncode  	 50	  32395067 ns/op	  59.90 MB/s",
@@ -453,14 +1318,53 @@ func TestStatBenchmarkResults(t *testing.T) {
 							Delta: -13.3,
 							Old:   "13.6ms ± 1%",
 							New:   "11.8ms ± 1%",
+							Unit:  "ns/op",
+							OldStats: benchcheck.BenchStats{
+								Mean:      1.3599058e+07,
+								Median:    1.35801495e+07,
+								Min:       1.3552735e+07,
+								Max:       1.3683198e+07,
+								Variation: 0.6187,
+								Samples:   4,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      1.17892886e+07,
+								Median:    1.1786159e+07,
+								Min:       1.1628583e+07,
+								Max:       1.1942588e+07,
+								Variation: 1.3631,
+								Samples:   5,
+							},
+							PValue:      0.0158,
+							Significant: true,
 						},
 						{
 							Name:  "JSONEncode",
 							Delta: 0.0,
 							Old:   "32.1ms ± 1%",
 							New:   "31.8ms ± 1%",
+							Unit:  "ns/op",
+							OldStats: benchcheck.BenchStats{
+								Mean:      3.21142985e+07,
+								Median:    3.21635525e+07,
+								Min:       3.1735022e+07,
+								Max:       3.2395067e+07,
+								Variation: 1.1810,
+								Samples:   4,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      3.17613552e+07,
+								Median:    3.1765634e+07,
+								Min:       3.1288355e+07,
+								Max:       3.2156552e+07,
+								Variation: 1.4892,
+								Samples:   5,
+							},
+							PValue: 0.2857,
 						},
 					},
+					Added:   []benchcheck.BenchName{{Name: "OnlyNew"}},
+					Removed: []benchcheck.BenchName{{Name: "OnlyOld"}},
 				},
 				{
 					Metric: "speed",
@@ -470,19 +1374,58 @@ func TestStatBenchmarkResults(t *testing.T) {
 							Delta: 15.35,
 							Old:   "56.4MB/s ± 1%",
 							New:   "65.1MB/s ± 1%",
+							Unit:  "MB/s",
+							OldStats: benchcheck.BenchStats{
+								Mean:      56.44,
+								Median:    56.52,
+								Min:       56.09,
+								Max:       56.63,
+								Variation: 0.6201,
+								Samples:   4,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      65.108,
+								Median:    65.12,
+								Min:       64.27,
+								Max:       66,
+								Variation: 1.3700,
+								Samples:   5,
+							},
+							PValue:      0.0158,
+							Significant: true,
 						},
 						{
 							Name:  "JSONEncode",
 							Delta: 0.0,
 							Old:   "60.4MB/s ± 1%",
 							New:   "61.1MB/s ± 2%",
+							Unit:  "MB/s",
+							OldStats: benchcheck.BenchStats{
+								Mean:      60.4275,
+								Median:    60.33,
+								Min:       59.9,
+								Max:       61.15,
+								Variation: 1.1956,
+								Samples:   4,
+							},
+							NewStats: benchcheck.BenchStats{
+								Mean:      61.102,
+								Median:    61.09,
+								Min:       60.34,
+								Max:       62.02,
+								Variation: 1.5024,
+								Samples:   5,
+							},
+							PValue: 0.2857,
 						},
 					},
+					Added:   []benchcheck.BenchName{{Name: "OnlyNew"}},
+					Removed: []benchcheck.BenchName{{Name: "OnlyOld"}},
 				},
 			},
 		},
 		{
-			name: "old and new with no common benchmarks produce empty stats",
+			name: "old and new with no common benchmarks are all added/removed",
 			oldres: []string{
 				"BenchmarkOnlyOld  	 50	  31735022 ns/op	  61.15 MB/s",
 				"BenchmarkOnlyOld  	 50	  31735022 ns/op	  61.15 MB/s",
@@ -495,10 +1438,23 @@ func TestStatBenchmarkResults(t *testing.T) {
 				"BenchmarkOnlyNew  	  50	  31735022 ns/op	  61.15 MB/s",
 				"BenchmarkOnlyNew  	  50	  31735022 ns/op	  61.15 MB/s",
 			},
-			want: []benchcheck.StatResult{},
+			want: []benchcheck.StatResult{
+				{
+					Metric:     "time/op",
+					BenchDiffs: []benchcheck.BenchDiff{},
+					Added:      []benchcheck.BenchName{{Name: "OnlyNew"}},
+					Removed:    []benchcheck.BenchName{{Name: "OnlyOld"}},
+				},
+				{
+					Metric:     "speed",
+					BenchDiffs: []benchcheck.BenchDiff{},
+					Added:      []benchcheck.BenchName{{Name: "OnlyNew"}},
+					Removed:    []benchcheck.BenchName{{Name: "OnlyOld"}},
+				},
+			},
 		},
 		{
-			name:   "old has no benchmarks produce empty stats",
+			name:   "old has no benchmarks are all added",
 			oldres: []string{},
 			newres: []string{
 				"BenchmarkOnlyNew  	  50	  31735022 ns/op	  61.15 MB/s",
@@ -506,10 +1462,21 @@ func TestStatBenchmarkResults(t *testing.T) {
 				"BenchmarkOnlyNew  	  50	  31735022 ns/op	  61.15 MB/s",
 				"BenchmarkOnlyNew  	  50	  31735022 ns/op	  61.15 MB/s",
 			},
-			want: []benchcheck.StatResult{},
+			want: []benchcheck.StatResult{
+				{
+					Metric:     "time/op",
+					BenchDiffs: []benchcheck.BenchDiff{},
+					Added:      []benchcheck.BenchName{{Name: "OnlyNew"}},
+				},
+				{
+					Metric:     "speed",
+					BenchDiffs: []benchcheck.BenchDiff{},
+					Added:      []benchcheck.BenchName{{Name: "OnlyNew"}},
+				},
+			},
 		},
 		{
-			name: "new has no benchmarks produce empty stats",
+			name: "new has no benchmarks are all removed",
 			oldres: []string{
 				"BenchmarkOnlyOld  	 50	  31735022 ns/op	  61.15 MB/s",
 				"BenchmarkOnlyOld  	 50	  31735022 ns/op	  61.15 MB/s",
@@ -517,7 +1484,18 @@ func TestStatBenchmarkResults(t *testing.T) {
 				"BenchmarkOnlyOld  	 50	  31735022 ns/op	  61.15 MB/s",
 			},
 			newres: []string{},
-			want:   []benchcheck.StatResult{},
+			want: []benchcheck.StatResult{
+				{
+					Metric:     "time/op",
+					BenchDiffs: []benchcheck.BenchDiff{},
+					Removed:    []benchcheck.BenchName{{Name: "OnlyOld"}},
+				},
+				{
+					Metric:     "speed",
+					BenchDiffs: []benchcheck.BenchDiff{},
+					Removed:    []benchcheck.BenchName{{Name: "OnlyOld"}},
+				},
+			},
 		},
 		{
 			name:   "no benchmarks produce empty stats",
@@ -541,6 +1519,309 @@ func TestStatBenchmarkResults(t *testing.T) {
 	}
 }
 
+func TestAddedRemovedBenchmarks(t *testing.T) {
+	t.Parallel()
+
+	results := []benchcheck.StatResult{
+		{
+			Metric: benchcheck.TimeMetric,
+			Added:  []benchcheck.BenchName{{Name: "EncodeFast"}},
+			Removed: []benchcheck.BenchName{
+				{Package: "example.com/gob", Name: "Encode"},
+				{Package: "example.com/json", Name: "Encode"},
+				{Name: "Decode"},
+			},
+		},
+		{
+			Metric:  benchcheck.AllocMetric,
+			Added:   []benchcheck.BenchName{{Name: "EncodeFast"}, {Name: "Marshal"}},
+			Removed: []benchcheck.BenchName{{Package: "example.com/gob", Name: "Encode"}, {Name: "Decode"}},
+		},
+	}
+
+	if diff := cmp.Diff([]benchcheck.BenchName{{Name: "EncodeFast"}, {Name: "Marshal"}}, benchcheck.AddedBenchmarks(results)); diff != "" {
+		t.Fatalf("added benchmarks: %s", diff)
+	}
+
+	removed := []string{}
+	for _, bench := range benchcheck.RemovedBenchmarks(results) {
+		removed = append(removed, bench.String())
+	}
+	want := []string{"example.com/gob.Encode", "example.com/json.Encode", "Decode"}
+	if diff := cmp.Diff(want, removed); diff != "" {
+		t.Fatalf("removed benchmarks: %s", diff)
+	}
+	if removed := benchcheck.RemovedBenchmarks(nil); len(removed) != 0 {
+		t.Fatalf("want no removed benchmarks, got: %v", removed)
+	}
+}
+
+func TestStatAddedRemovedPackages(t *testing.T) {
+	t.Parallel()
+
+	oldres := benchcheck.BenchResults{
+		"pkg: example.com/gob",
+		"BenchmarkEncode  	 50	  31735022 ns/op",
+		"pkg: example.com/json",
+		"BenchmarkEncode  	 50	  31735022 ns/op",
+	}
+	newres := benchcheck.BenchResults{
+		"pkg: example.com/gob",
+		"BenchmarkEncode  	 50	  31735022 ns/op",
+		"BenchmarkDecode  	 50	  31735022 ns/op",
+	}
+
+	results, err := benchcheck.Stat(oldres, newres)
+	assertNoError(t, err)
+
+	added := []benchcheck.BenchName{{Package: "example.com/gob", Name: "Decode"}}
+	if diff := cmp.Diff(added, benchcheck.AddedBenchmarks(results)); diff != "" {
+		t.Fatalf("added benchmarks: %s", diff)
+	}
+	// Encode was removed only from the json package.
+	removed := []benchcheck.BenchName{{Package: "example.com/json", Name: "Encode"}}
+	if diff := cmp.Diff(removed, benchcheck.RemovedBenchmarks(results)); diff != "" {
+		t.Fatalf("removed benchmarks: %s", diff)
+	}
+}
+
+func stripProcCount(name string) string {
+	// Benchmark names have a -N suffix with the GOMAXPROCS
+	// used to run them, unless it is 1.
+	if i := strings.LastIndex(name, "-"); i != -1 {
+		return name[:i]
+	}
+	return name
+}
+
+func TestStatFiles(t *testing.T) {
+	t.Parallel()
+
+	oldf, err := os.Open(filepath.Join("testdata", "old.txt"))
+	assert.NoError(t, err)
+	defer oldf.Close()
+
+	newf, err := os.Open(filepath.Join("testdata", "new.txt"))
+	assert.NoError(t, err)
+	defer newf.Close()
+
+	got, err := benchcheck.StatFiles(oldf, newf)
+	assertNoError(t, err)
+
+	want := []benchcheck.StatResult{
+		{
+			Metric: "time/op",
+			BenchDiffs: []benchcheck.BenchDiff{
+				{
+					Name:    "GobEncode",
+					Package: "example.com/encoding",
+					Delta:   -13.3,
+					Old:     "13.6ms ± 1%",
+					New:     "11.8ms ± 1%",
+					Unit:    "ns/op",
+					OldStats: benchcheck.BenchStats{
+						Mean:      1.3599058e+07,
+						Median:    1.35801495e+07,
+						Min:       1.3552735e+07,
+						Max:       1.3683198e+07,
+						Variation: 0.6187,
+						Samples:   4,
+					},
+					NewStats: benchcheck.BenchStats{
+						Mean:      1.17892886e+07,
+						Median:    1.1786159e+07,
+						Min:       1.1628583e+07,
+						Max:       1.1942588e+07,
+						Variation: 1.3631,
+						Samples:   5,
+					},
+					PValue:      0.0158,
+					Significant: true,
+				},
+				{
+					Name:    "JSONEncode",
+					Package: "example.com/encoding",
+					Delta:   0.0,
+					Old:     "32.1ms ± 1%",
+					New:     "31.8ms ± 1%",
+					Unit:    "ns/op",
+					OldStats: benchcheck.BenchStats{
+						Mean:      3.21142985e+07,
+						Median:    3.21635525e+07,
+						Min:       3.1735022e+07,
+						Max:       3.2395067e+07,
+						Variation: 1.1810,
+						Samples:   4,
+					},
+					NewStats: benchcheck.BenchStats{
+						Mean:      3.17613552e+07,
+						Median:    3.1765634e+07,
+						Min:       3.1288355e+07,
+						Max:       3.2156552e+07,
+						Variation: 1.4892,
+						Samples:   5,
+					},
+					PValue: 0.2857,
+				},
+			},
+		},
+		{
+			Metric: "speed",
+			BenchDiffs: []benchcheck.BenchDiff{
+				{
+					Name:    "GobEncode",
+					Package: "example.com/encoding",
+					Delta:   15.35,
+					Old:     "56.4MB/s ± 1%",
+					New:     "65.1MB/s ± 1%",
+					Unit:    "MB/s",
+					OldStats: benchcheck.BenchStats{
+						Mean:      56.44,
+						Median:    56.52,
+						Min:       56.09,
+						Max:       56.63,
+						Variation: 0.6201,
+						Samples:   4,
+					},
+					NewStats: benchcheck.BenchStats{
+						Mean:      65.108,
+						Median:    65.12,
+						Min:       64.27,
+						Max:       66,
+						Variation: 1.3700,
+						Samples:   5,
+					},
+					PValue:      0.0158,
+					Significant: true,
+				},
+				{
+					Name:    "JSONEncode",
+					Package: "example.com/encoding",
+					Delta:   0.0,
+					Old:     "60.4MB/s ± 1%",
+					New:     "61.1MB/s ± 2%",
+					Unit:    "MB/s",
+					OldStats: benchcheck.BenchStats{
+						Mean:      60.4275,
+						Median:    60.33,
+						Min:       59.9,
+						Max:       61.15,
+						Variation: 1.1956,
+						Samples:   4,
+					},
+					NewStats: benchcheck.BenchStats{
+						Mean:      61.102,
+						Median:    61.09,
+						Min:       60.34,
+						Max:       62.02,
+						Variation: 1.5024,
+						Samples:   5,
+					},
+					PValue: 0.2857,
+				},
+			},
+		},
+	}
+	assertEqualWithFloat(t, got, want)
+}
+
+func TestParseBenchResults(t *testing.T) {
+	t.Parallel()
+
+	const output = `goos: linux
+goarch: amd64
+pkg: example.com/pkg
+BenchmarkA-8   	 100	  13552735 ns/op
+--- FAIL: TestSomething
+BenchmarkB-8   	 50	  32395067 ns/op
+BenchmarkC-8   	panic: boom
+BenchmarkD
+--- FAIL: BenchmarkD
+PASS
+ok  	example.com/pkg	2.345s
+`
+	got, err := benchcheck.ParseBenchResults(strings.NewReader(output))
+	assertNoError(t, err)
+
+	want := benchcheck.BenchResults{
+		"goos: linux",
+		"goarch: amd64",
+		"pkg: example.com/pkg",
+		"BenchmarkA-8   	 100	  13552735 ns/op",
+		"BenchmarkB-8   	 50	  32395067 ns/op",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestStatPackages(t *testing.T) {
+	t.Parallel()
+
+	results := func(pkgs ...string) benchcheck.BenchResults {
+		res := benchcheck.BenchResults{"goos: linux"}
+		for i, pkg := range pkgs {
+			res = append(res, "pkg: "+pkg)
+			for n := 0; n < 3; n++ {
+				res = append(res, fmt.Sprintf("BenchmarkEncode-8  	 100	  %d ns/op	  %d widgets/op", (i+1)*1000, i+1))
+			}
+			res = append(res, "BenchmarkEncode-8  	panic: boom")
+		}
+		return res
+	}
+
+	got, err := benchcheck.Stat(
+		results("example.com/a", "example.com/b"),
+		results("example.com/a", "example.com/b"),
+	)
+	assertNoError(t, err)
+
+	type bench struct {
+		pkg  string
+		name string
+		mean float64
+	}
+	metrics := map[string][]bench{}
+	for _, result := range got {
+		for _, diff := range result.BenchDiffs {
+			metrics[result.Metric] = append(metrics[result.Metric], bench{
+				pkg:  diff.Package,
+				name: diff.Name,
+				mean: diff.NewStats.Mean,
+			})
+		}
+	}
+
+	want := map[string][]bench{
+		benchcheck.TimeMetric: {
+			{pkg: "example.com/a", name: "Encode-8", mean: 1000},
+			{pkg: "example.com/b", name: "Encode-8", mean: 2000},
+		},
+		"widgets/op": {
+			{pkg: "example.com/a", name: "Encode-8", mean: 1},
+			{pkg: "example.com/b", name: "Encode-8", mean: 2},
+		},
+	}
+	if diff := cmp.Diff(want, metrics, cmp.AllowUnexported(bench{})); diff != "" {
+		t.Fatalf("want benchmarks grouped by package: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"example.com/a", "example.com/b"}, got[0].Packages()); diff != "" {
+		t.Fatalf("packages: %s", diff)
+	}
+}
+
+// benchmarkResults returns only the benchmark results,
+// without the configuration lines.
+func benchmarkResults(res benchcheck.BenchResults) []string {
+	var results []string
+	for _, r := range res {
+		if strings.HasPrefix(r, "Benchmark") {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
 func assertNoError(t *testing.T, err error, details ...interface{}) {
 	t.Helper()
 
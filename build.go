@@ -0,0 +1,173 @@
+package benchcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// testBinary is the compiled test binary of a single package.
+type testBinary struct {
+	// path of the compiled binary.
+	path string
+	// dir of the package, test binaries run inside it, just like go test.
+	dir string
+	// pkg is the import path of the package.
+	pkg string
+}
+
+// moduleBuild has the test binaries of the packages of a module.
+type moduleBuild struct {
+	dir      string
+	binaries []testBinary
+	// failures are the packages whose tests failed to build when
+	// building with RunOptions.Tolerant, reported on every run.
+	failures []PackageFailure
+}
+
+// buildModule compiles the test binaries of all packages selected by the
+// given options on the module, so benchmarks can run multiple times
+// without compiling and linking the tests again on each run.
+// The returned build must be closed after use.
+//
+// With RunOptions.Tolerant packages whose tests fail to build are
+// skipped, and reported as failures by all runs of the build.
+//
+// Any errors running "go" can be inspected in detail by
+// checking if the returned error is a *CmdError.
+func buildModule(ctx context.Context, mod Module, opts RunOptions) (moduleBuild, error) {
+	pkgs, err := listTestPackages(ctx, mod, opts)
+	if err != nil {
+		return moduleBuild{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "benchcheck-build-")
+	if err != nil {
+		return moduleBuild{}, fmt.Errorf("creating build dir: %v", err)
+	}
+
+	build := moduleBuild{dir: dir}
+
+	for i, pkg := range pkgs {
+		bin := testBinary{
+			path: filepath.Join(dir, fmt.Sprintf("pkg%d.test", i)),
+			dir:  pkg.dir,
+			pkg:  pkg.importPath,
+		}
+
+		args := append([]string{"test", "-c", "-o", bin.path}, opts.buildFlags()...)
+		cmd := command(ctx, "go", append(args, pkg.importPath)...)
+		cmd.Dir = mod.Path()
+
+		if _, err := combinedOutput(ctx, cmd); err != nil {
+			var cmderr *CmdError
+			if opts.Tolerant && errors.As(err, &cmderr) {
+				build.failures = append(build.failures, PackageFailure{
+					Package: pkg.importPath,
+					Output:  cmderr.Output,
+				})
+				continue
+			}
+			_ = build.Close()
+			return moduleBuild{}, err
+		}
+		build.binaries = append(build.binaries, bin)
+	}
+
+	return build, nil
+}
+
+// Run runs the benchmarks of all test binaries of the build, which
+// are always the ones of the module the build was created from.
+//
+// Any errors running the binaries can be inspected in detail by
+// checking if the returned error is a *CmdError, or a *CanceledError
+// if the context is done before the binaries finish.
+func (b moduleBuild) Run(ctx context.Context, _ Module, opts RunOptions) (BenchResults, error) {
+	return b.runStream(ctx, Module{}, opts, func(string) {})
+}
+
+// runStream works like Run, calling onResult with each benchmark
+// result as soon as the benchmark finishes.
+func (b moduleBuild) runStream(ctx context.Context, _ Module, opts RunOptions, onResult func(string)) (BenchResults, error) {
+	results := BenchResults{}
+	failures := append([]PackageFailure(nil), b.failures...)
+
+	for _, bin := range b.binaries {
+		cmd := command(ctx, bin.path, opts.testBinaryArgs()...)
+		cmd.Dir = bin.dir
+
+		_, err := streamOutput(ctx, cmd, func(line string) {
+			if results.parse(line) {
+				onResult(line)
+			}
+		})
+		if err != nil {
+			var cmderr *CmdError
+			if !opts.Tolerant || !errors.As(err, &cmderr) {
+				return nil, err
+			}
+			failures = append(failures, PackageFailure{
+				Package: bin.pkg,
+				Output:  cmderr.Output,
+			})
+		}
+	}
+
+	if len(failures) > 0 {
+		return results, &PartialError{Failures: failures}
+	}
+	return results, nil
+}
+
+// packages returns the import paths of the built packages.
+func (b moduleBuild) packages() []string {
+	pkgs := make([]string, len(b.binaries))
+	for i, bin := range b.binaries {
+		pkgs[i] = bin.pkg
+	}
+	return pkgs
+}
+
+// Close removes all the test binaries of the build.
+func (b moduleBuild) Close() error {
+	return os.RemoveAll(b.dir)
+}
+
+type testPackage struct {
+	importPath string
+	dir        string
+}
+
+// listTestPackages lists the packages selected by the given options
+// that have test files, since only those have benchmarks.
+// Packages with errors, like missing imports, are listed too so
+// building their tests reports the errors of each package.
+func listTestPackages(ctx context.Context, mod Module, opts RunOptions) ([]testPackage, error) {
+	const format = "{{if or .TestGoFiles .XTestGoFiles}}{{.ImportPath}}\t{{.Dir}}{{end}}"
+
+	args := append([]string{"list", "-e", "-f", format}, opts.buildFlags()...)
+	cmd := command(ctx, "go", append(args, opts.packages()...)...)
+	cmd.Dir = mod.Path()
+
+	out, err := output(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := []testPackage{}
+	for _, line := range strings.Split(string(out), "\n") {
+		parsed := strings.Split(line, "\t")
+		if len(parsed) != 2 {
+			continue
+		}
+		pkgs = append(pkgs, testPackage{
+			importPath: parsed[0],
+			dir:        parsed[1],
+		})
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,183 @@
+package benchcheck_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestStatModulesPackages(t *testing.T) {
+	t.Parallel()
+
+	mod := benchModule(t, map[string]string{
+		"a/a_test.go": `package a
+
+import "testing"
+
+func BenchmarkA(b *testing.B) {}
+`,
+		"b/b_test.go": `package b_test
+
+import "testing"
+
+func BenchmarkB(b *testing.B) {}
+`,
+		"notests/notests.go": "package notests\n",
+		"tagged/tagged_test.go": `//go:build custom
+// +build custom
+
+package tagged
+
+import "testing"
+
+func BenchmarkTagged(b *testing.B) {}
+`,
+	})
+
+	type testcase struct {
+		name string
+		opts benchcheck.RunOptions
+		want []string
+	}
+
+	tcases := []testcase{
+		{
+			name: "all packages",
+			want: []string{"BenchmarkA", "BenchmarkB"},
+		},
+		{
+			name: "filtered packages",
+			opts: benchcheck.RunOptions{Packages: []string{"./b", "./notests"}},
+			want: []string{"BenchmarkB"},
+		},
+		{
+			name: "tagged packages",
+			opts: benchcheck.RunOptions{Tags: []string{"custom"}},
+			want: []string{"BenchmarkA", "BenchmarkB", "BenchmarkTagged"},
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := tcase.opts
+			opts.Count = 2
+			opts.BenchTime = "1x"
+
+			runs := 0
+			_, err := benchcheck.StatModules(mod, mod,
+				benchcheck.WithRunOptions(opts),
+				benchcheck.OnRun(func(run benchcheck.BenchRun) {
+					runs++
+
+					results := benchmarkResults(run.Results)
+					got := make([]string, len(results))
+					for i, r := range results {
+						got[i] = stripProcCount(strings.Fields(r)[0])
+					}
+					sort.Strings(got)
+
+					if diff := cmp.Diff(tcase.want, got); diff != "" {
+						t.Errorf("run %d: got benchmarks %v, want %v: %s", run.Seq, got, tcase.want, diff)
+					}
+				}),
+			)
+			assertNoError(t, err)
+
+			if runs != 4 {
+				t.Fatalf("got %d runs, want 4", runs)
+			}
+		})
+	}
+}
+
+func TestStatModulesSameNames(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{}
+	for _, pkg := range []string{"a", "b"} {
+		files[pkg+"/"+pkg+"_test.go"] = `package ` + pkg + `
+
+import "testing"
+
+func BenchmarkEncode(b *testing.B) {
+	b.ReportMetric(42, "widgets/op")
+}
+`
+	}
+	mod := benchModule(t, files)
+
+	stats, err := benchcheck.StatModules(mod, mod, benchcheck.WithRunOptions(benchcheck.RunOptions{
+		Count:     2,
+		BenchTime: "1x",
+	}))
+	assertNoError(t, err)
+
+	got := map[string][]string{}
+	for _, result := range stats {
+		for _, diff := range result.BenchDiffs {
+			got[result.Metric] = append(got[result.Metric], diff.Package+"."+stripProcCount(diff.Name))
+		}
+	}
+	for _, metric := range []string{benchcheck.TimeMetric, "widgets/op"} {
+		want := []string{"bench/a.Encode", "bench/b.Encode"}
+		if diff := cmp.Diff(want, got[metric]); diff != "" {
+			t.Errorf("%s: want benchmarks of each package: %s", metric, diff)
+		}
+	}
+}
+
+func TestStatModulesBuildsOnce(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"a/a_test.go": `package a
+
+import "testing"
+
+func BenchmarkA(b *testing.B) {}
+`,
+		"b/b_test.go": `package b
+
+import "testing"
+
+func BenchmarkB(b *testing.B) {}
+`,
+	}
+	oldmod := benchModule(t, files)
+	newmod := benchModule(t, files)
+
+	const count = 3
+
+	builds := map[benchcheck.Side]int{}
+	runs := map[benchcheck.Side]int{}
+	_, err := benchcheck.StatModules(oldmod, newmod,
+		benchcheck.WithRunOptions(benchcheck.RunOptions{Count: count, BenchTime: "1x"}),
+		benchcheck.OnEvent(func(event benchcheck.Event) {
+			switch event.Kind {
+			case benchcheck.BuildStarted:
+				builds[event.Side]++
+			case benchcheck.BuildFinished:
+				// Runs can't compile anything once the modules are
+				// not Go modules anymore, only the built binaries work.
+				assert.NoError(t, os.Remove(filepath.Join(event.Module.Path(), "go.mod")))
+			case benchcheck.RunFinished:
+				runs[event.Side]++
+			}
+		}),
+	)
+	assertNoError(t, err)
+
+	for _, side := range []benchcheck.Side{benchcheck.OldSide, benchcheck.NewSide} {
+		assert.EqualInts(t, 1, builds[side], "want a single build of the %s module", side)
+		assert.EqualInts(t, count, runs[side], "want all runs of the %s module", side)
+	}
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/madlambda/benchcheck"
 )
@@ -33,16 +39,127 @@ func (c *checkList) Set(val string) error {
 	return nil
 }
 
+// Exit codes of benchcheck, so it can be used as a gate on CI.
+const (
+	exitOK = iota
+	exitCheckFailed
+	exitUsage
+	exitRunFailed
+)
+
+// Exit codes when benchcheck is interrupted by a signal, following
+// the shell convention of 128 + the signal number.
+const (
+	exitInterrupted = 128 + 2  // SIGINT
+	exitTerminated  = 128 + 15 // SIGTERM
+)
+
+// exitSignaled is the exit code for the signal that interrupted
+// benchcheck, see notifySignals.
+var exitSignaled int32 = exitInterrupted
+
+// deltaCheck is a shorthand flag for checks on a specific metric,
+// like "-time-delta +10%" instead of "-check time/op=+10%".
+type deltaCheck struct {
+	metric string
+	checks *checkList
+}
+
+func (d deltaCheck) String() string {
+	return ""
+}
+
+func (d deltaCheck) Set(val string) error {
+	return d.checks.Set(d.metric + "=" + val)
+}
+
+// stringList is a flag that can be provided multiple times.
+type stringList []string
+
+func (s *stringList) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(val string) error {
+	*s = append(*s, val)
+	return nil
+}
+
+// packageFailurePolicy defines what happens when the benchmarks of
+// some packages fail, like when a benchmark panics.
+type packageFailurePolicy string
+
+const (
+	// abortOnPackageFailure discards all results, exiting with exitRunFailed.
+	abortOnPackageFailure packageFailurePolicy = "abort"
+	// failOnPackageFailure checks and reports the results of the other
+	// packages, but still exits with exitRunFailed.
+	failOnPackageFailure packageFailurePolicy = "fail"
+	// warnOnPackageFailure checks and reports the results of the other
+	// packages, exiting according to the checks.
+	warnOnPackageFailure packageFailurePolicy = "warn"
+)
+
+func (p *packageFailurePolicy) String() string {
+	if p == nil {
+		return ""
+	}
+	return string(*p)
+}
+
+func (p *packageFailurePolicy) Set(val string) error {
+	switch policy := packageFailurePolicy(val); policy {
+	case abortOnPackageFailure, failOnPackageFailure, warnOnPackageFailure:
+		*p = policy
+		return nil
+	}
+	return fmt.Errorf("unknown policy %q, want %s, %s or %s", val,
+		abortOnPackageFailure, failOnPackageFailure, warnOnPackageFailure)
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == compareCmd {
+		compare(os.Args[2:])
+		return
+	}
+
 	version := flag.Bool("version", false, "show version")
 	mod := flag.String("mod", "", "module to be bench checked")
+	repo := flag.String("repo", "", "local git repository to be bench checked, used by default (with \".\") when -mod is not provided")
 	oldRev := flag.String("old", "", "the old revision to compare")
-	newRev := flag.String("new", "", "the new revision to compare")
+	newRev := flag.String("new", "", fmt.Sprintf(
+		"the new revision to compare, when using -repo the %q revision means the working tree as is",
+		workingTree))
 
-	checks := checkList{}
-	flag.Var(&checks, "check", fmt.Sprintf(
-		"check to be performed, defined in the form: %s. Eg: time/op=10%%",
-		benchcheck.CheckerFmt))
+	bench := flag.String("bench", ".", "regular expression selecting the benchmarks to run, as in go test -bench")
+	run := flag.String("run", "^$", "regular expression selecting the tests to run, as in go test -run")
+	tags := flag.String("tags", "", "comma separated list of build tags, as in go test -tags")
+	count := flag.Int("count", benchcheck.DefaultCount, "number of times each benchmark runs, as in go test -count")
+	benchtime := flag.String("benchtime", "", "time each benchmark runs, like 1s or 100x, as in go test -benchtime")
+	cpu := flag.String("cpu", "", "comma separated list of GOMAXPROCS values, as in go test -cpu")
+	timeout := flag.Duration("timeout", 0, "timeout of each go test execution, as in go test -timeout")
+	schedule := flag.String("schedule", benchcheck.Interleaved.String(), fmt.Sprintf(
+		"order of old and new benchmark runs: %s, %s or %s",
+		benchcheck.Interleaved, benchcheck.Randomized, benchcheck.Sequential))
+	noBenchMem := flag.Bool("no-benchmem", false, "disable memory allocation stats, enabled by default as in go test -benchmem")
+
+	pkgs := stringList{}
+	flag.Var(&pkgs, "pkg", "package pattern to be benchmarked, like ./pkg/... (can be provided multiple times, default ./...)")
+
+	onPackageFailure := abortOnPackageFailure
+	flag.Var(&onPackageFailure, "on-package-failure", fmt.Sprintf(
+		"what to do when benchmarks of some packages fail: %s discards all results, %s reports the other packages and fails, %s reports the other packages and only warns",
+		abortOnPackageFailure, failOnPackageFailure, warnOnPackageFailure))
+
+	checks := &checkFlags{}
+	addCheckFlags(flag.CommandLine, checks)
+	statFlags := addStatFlags(flag.CommandLine)
+	out := addOutputFlags(flag.CommandLine)
+	conf := addConfigFlags(flag.CommandLine)
+	showProgress := addProgressFlag(flag.CommandLine)
 
 	flag.Parse()
 
@@ -53,40 +170,309 @@ func main() {
 
 	if len(os.Args) <= 1 {
 		flag.Usage()
-		return
+		os.Exit(exitUsage)
 	}
 
-	if *mod == "" {
-		log.Fatal("-mod is obligatory")
+	if *mod != "" && *repo != "" {
+		usageError(flag.CommandLine, "-mod and -repo are mutually exclusive")
+	}
+	if *mod == "" && *repo == "" {
+		*repo = "."
 	}
 	if *oldRev == "" {
-		log.Fatal("-old is obligatory")
+		usageError(flag.CommandLine, "-old is obligatory")
 	}
 	if *newRev == "" {
-		log.Fatal("-new is obligatory")
+		usageError(flag.CommandLine, "-new is obligatory")
 	}
 
-	results, err := benchcheck.StatModule(*mod, *oldRev, *newRev)
+	configDir := *repo
+	if configDir == "" {
+		configDir = "."
+	}
+	config, err := conf.load(configDir)
 	if err != nil {
-		var cmderr *benchcheck.CmdError
-		if errors.As(err, &cmderr) {
-			fmt.Fprintf(os.Stderr, "failed to run: %s", cmderr.Cmd)
-			fmt.Fprintf(os.Stderr, "error: %s", cmderr.Err)
-			fmt.Fprintf(os.Stderr, "cmd output: %s", cmderr.Output)
-		} else {
-			log.Fatal(err)
+		usageError(flag.CommandLine, err.Error())
+	}
+	set := setFlags(flag.CommandLine)
+
+	runOpts := config.Run
+	if set["bench"] || runOpts.Bench == "" {
+		runOpts.Bench = *bench
+	}
+	if set["run"] || runOpts.Run == "" {
+		runOpts.Run = *run
+	}
+	if set["pkg"] {
+		runOpts.Packages = pkgs
+	}
+	if set["count"] || runOpts.Count == 0 {
+		runOpts.Count = *count
+	}
+	if set["benchtime"] {
+		runOpts.BenchTime = *benchtime
+	}
+	if set["timeout"] {
+		runOpts.Timeout = *timeout
+	}
+	if set["no-benchmem"] {
+		runOpts.NoBenchMem = *noBenchMem
+	}
+	if set["tags"] {
+		runOpts.Tags = nil
+		if *tags != "" {
+			runOpts.Tags = strings.Split(*tags, ",")
+		}
+	}
+	if set["cpu"] {
+		runOpts.CPU = nil
+		for _, v := range strings.Split(*cpu, ",") {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				usageError(flag.CommandLine, fmt.Sprintf("invalid -cpu %q: %v", *cpu, err))
+			}
+			runOpts.CPU = append(runOpts.CPU, n)
+		}
+	}
+	runOpts.Tolerant = onPackageFailure != abortOnPackageFailure
+	if err := runOpts.Validate(); err != nil {
+		usageError(flag.CommandLine, err.Error())
+	}
+	statOpts := statOptions(config.Stat, statFlags, set)
+	if err := statOpts.Validate(); err != nil {
+		usageError(flag.CommandLine, err.Error())
+	}
+	runSchedule := config.Schedule
+	if set["schedule"] {
+		runSchedule, err = benchcheck.ParseSchedule(*schedule)
+		if err != nil {
+			usageError(flag.CommandLine, err.Error())
 		}
 	}
 
-	for _, result := range results {
-		fmt.Printf("metric: %s\n", result.Metric)
-		for _, diff := range result.BenchDiffs {
-			fmt.Println(diff)
+	// Interrupting benchcheck kills all running commands, like go test
+	// and the test binaries, and removes temporary worktrees and builds.
+	ctx, stop := notifySignals()
+	defer stop()
+
+	var results []benchcheck.StatResult
+	opts := []benchcheck.Option{
+		benchcheck.WithRunOptions(runOpts),
+		benchcheck.WithSchedule(runSchedule),
+		benchcheck.WithStatOptions(statOpts),
+	}
+	prog := progress{w: io.Discard}
+	if *showProgress {
+		prog.w = os.Stderr
+		opts = append(opts, benchcheck.OnEvent(prog.render))
+	}
+	if *repo != "" {
+		results, err = statRepo(ctx, *repo, *oldRev, *newRev, prog, opts...)
+	} else {
+		results, err = benchcheck.StatModuleContext(ctx, *mod, *oldRev, *newRev, opts...)
+	}
+	var partial *benchcheck.PartialError
+	var failures []benchcheck.PackageFailure
+	if errors.As(err, &partial) {
+		failures = partial.Failures
+	} else if err != nil {
+		runError(err)
+	}
+
+	code := checkResults(results, failures, checks, config, out)
+	if len(failures) > 0 && onPackageFailure == failOnPackageFailure {
+		code = exitRunFailed
+	}
+	os.Exit(code)
+}
+
+// checkFlags are all the flags defining the checks to be performed.
+type checkFlags struct {
+	checks              checkList
+	requireSignificance bool
+	failInconclusive    bool
+	failRemoved         bool
+}
+
+// checkers returns the configured checkers plus the ones defined by
+// the flags. The significance flags apply to all of them, but they only
+// make checkers stricter, so a configured checker that already fails on
+// inconclusive benchmarks keeps failing on them.
+func (f *checkFlags) checkers(configured []benchcheck.Checker) []benchcheck.Checker {
+	checkers := append(append([]benchcheck.Checker{}, configured...), f.checks...)
+	if !f.requireSignificance && !f.failInconclusive {
+		return checkers
+	}
+	for i, check := range checkers {
+		checkers[i] = check.RequireSignificance(f.failInconclusive || check.FailsInconclusive())
+	}
+	return checkers
+}
+
+// addCheckFlags adds the -check flag, its shorthands and the
+// significance flags to the flag set.
+func addCheckFlags(fs *flag.FlagSet, flags *checkFlags) {
+	checks := &flags.checks
+	fs.Var(checks, "check", fmt.Sprintf(
+		"check to be performed, defined in the form: %s. Eg: time/op=10%% or BenchmarkEncode.*:time/op=2%%",
+		benchcheck.CheckerFmt))
+	for _, shorthand := range []struct {
+		name   string
+		metric string
+	}{
+		{name: "time-delta", metric: benchcheck.TimeMetric},
+		{name: "alloc-delta", metric: benchcheck.AllocMetric},
+		{name: "allocs-delta", metric: benchcheck.AllocsMetric},
+	} {
+		fs.Var(deltaCheck{metric: shorthand.metric, checks: checks}, shorthand.name, fmt.Sprintf(
+			"shorthand for -check %s=<delta>. Eg: -%s +10%%", shorthand.metric, shorthand.name))
+	}
+	fs.BoolVar(&flags.requireSignificance, "require-significance", false,
+		"only fail checks on statistically significant deltas, reporting the others as inconclusive")
+	fs.BoolVar(&flags.failInconclusive, "fail-inconclusive", false,
+		"fail checks with inconclusive benchmarks, implies -require-significance")
+	fs.BoolVar(&flags.failRemoved, "fail-removed", false,
+		"fail if benchmarks of the old version are missing on the new one, like deleted or renamed benchmarks")
+}
+
+// checkResults performs all configured checks and the ones defined by
+// flags on the results, without the ignored benchmarks, and writes them
+// along with the failed packages on the given output, returning the
+// exit code.
+func checkResults(
+	results []benchcheck.StatResult,
+	failures []benchcheck.PackageFailure,
+	checks *checkFlags,
+	config benchcheck.Config,
+	out output,
+) int {
+	results = config.Filter(results)
+	report := benchcheck.NewReport(results, checks.checkers(config.Checkers))
+	report.Failures = failures
+	report.FailRemoved = checks.failRemoved || config.FailRemoved
+
+	if err := out.write(report); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing %s report: %v\n", out.format, err)
+		return exitRunFailed
+	}
+
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "benchmarks failed on package: %s\n", failure)
+		fmt.Fprintf(os.Stderr, "package output: %s\n", failure.Output)
+	}
+
+	for _, check := range report.Checks {
+		if check.Status() != benchcheck.CheckPassed {
+			fmt.Fprintln(os.Stderr, check)
+		}
+	}
+
+	if removed := benchcheck.RemovedBenchmarks(results); len(removed) > 0 {
+		status := "warning"
+		if report.FailRemoved {
+			status = "check failed"
 		}
-		for _, check := range checks {
-			if !check.Do(result) {
-				fmt.Printf("check failed: %s", check)
+		fmt.Fprintf(os.Stderr, "%s: removed benchmarks: %s\n", status, joinBenchNames(removed))
+	}
+	if added := benchcheck.AddedBenchmarks(results); len(added) > 0 {
+		fmt.Fprintf(os.Stderr, "added benchmarks: %s\n", joinBenchNames(added))
+	}
+
+	if !report.Passed() {
+		return exitCheckFailed
+	}
+	return exitOK
+}
+
+// joinBenchNames joins the package qualified names of the benchmarks.
+func joinBenchNames(benchs []benchcheck.BenchName) string {
+	names := make([]string, len(benchs))
+	for i, bench := range benchs {
+		names[i] = bench.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// notifySignals returns a context that is canceled when benchcheck
+// receives SIGINT or SIGTERM, setting exitSignaled accordingly.
+// Calling stop stops receiving the signals.
+func notifySignals() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			if sig == syscall.SIGTERM {
+				atomic.StoreInt32(&exitSignaled, exitTerminated)
 			}
+			cancel()
+		case <-ctx.Done():
 		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+func usageError(fs *flag.FlagSet, msg string) {
+	fmt.Fprintf(os.Stderr, "usage error: %s\n", msg)
+	fs.Usage()
+	os.Exit(exitUsage)
+}
+
+func runError(err error) {
+	var canceled *benchcheck.CanceledError
+	if errors.As(err, &canceled) {
+		fmt.Fprintf(os.Stderr, "interrupted: %s\n", canceled.Cmd)
+		os.Exit(int(atomic.LoadInt32(&exitSignaled)))
+	}
+
+	var cmderr *benchcheck.CmdError
+	if errors.As(err, &cmderr) {
+		fmt.Fprintf(os.Stderr, "failed to run: %s\n", cmderr.Cmd)
+		fmt.Fprintf(os.Stderr, "error: %s\n", cmderr.Err)
+		fmt.Fprintf(os.Stderr, "cmd output: %s\n", cmderr.Output)
+	} else {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+	os.Exit(exitRunFailed)
+}
+
+// workingTree is the revision used to refer to the
+// repository working tree as is, including uncommitted changes.
+const workingTree = "."
+
+func statRepo(ctx context.Context, repo string, oldrev, newrev string, prog progress, opts ...benchcheck.Option) ([]benchcheck.StatResult, error) {
+	oldmod, err := getRevision(ctx, repo, oldrev)
+	if err != nil {
+		return nil, fmt.Errorf("getting old revision: %w", err)
+	}
+	defer closeModule(oldmod)
+	prog.render(benchcheck.Event{Kind: benchcheck.ModuleResolved, Side: benchcheck.OldSide, Module: oldmod})
+
+	newmod, err := getRevision(ctx, repo, newrev)
+	if err != nil {
+		return nil, fmt.Errorf("getting new revision: %w", err)
+	}
+	defer closeModule(newmod)
+	prog.render(benchcheck.Event{Kind: benchcheck.ModuleResolved, Side: benchcheck.NewSide, Module: newmod})
+
+	return benchcheck.StatModulesContext(ctx, oldmod, newmod, opts...)
+}
+
+func getRevision(ctx context.Context, repo string, rev string) (benchcheck.Module, error) {
+	if rev == workingTree {
+		return benchcheck.LocalModule(repo)
+	}
+	return benchcheck.GitRevisionContext(ctx, repo, rev)
+}
+
+func closeModule(mod benchcheck.Module) {
+	if err := mod.Close(); err != nil {
+		log.Printf("closing %v: %v", mod, err)
 	}
 }
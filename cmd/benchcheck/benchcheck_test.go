@@ -0,0 +1,917 @@
+package main_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var benchcheckBin string
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "benchcheck-cmd-test-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating temp dir: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	benchcheckBin = filepath.Join(dir, "benchcheck")
+
+	out, err := exec.Command("go", "build", "-o", benchcheckBin, ".").CombinedOutput()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "building benchcheck: %v: %s\n", err, out)
+		return 1
+	}
+	return m.Run()
+}
+
+func TestExitCodes(t *testing.T) {
+	t.Parallel()
+
+	const (
+		fastBench = "time.Sleep(time.Millisecond)"
+		slowBench = "time.Sleep(5 * time.Millisecond)"
+	)
+
+	repo := t.TempDir()
+	commitModule(t, repo, fastBench)
+	runGit(t, repo, "tag", "fast")
+	commitModule(t, repo, slowBench)
+	runGit(t, repo, "tag", "slow")
+	writeFile(t, filepath.Join(repo, "bench_test.go"), "package bench\n\nwont compile")
+	runGit(t, repo, "commit", "-q", "-a", "-m", "broken")
+	runGit(t, repo, "tag", "broken")
+
+	type testcase struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}
+
+	tcases := []testcase{
+		{
+			name:     "no args",
+			wantCode: 2,
+		},
+		{
+			name:       "invalid count",
+			args:       []string{"-repo", repo, "-old", "fast", "-new", "slow", "-count", "-1"},
+			wantCode:   2,
+			wantStderr: "count",
+		},
+		{
+			name:       "invalid benchtime",
+			args:       []string{"-repo", repo, "-old", "fast", "-new", "slow", "-benchtime", "fast"},
+			wantCode:   2,
+			wantStderr: "benchtime",
+		},
+		{
+			name:       "invalid cpu",
+			args:       []string{"-repo", repo, "-old", "fast", "-new", "slow", "-cpu", "1,two"},
+			wantCode:   2,
+			wantStderr: "-cpu",
+		},
+		{
+			name:       "invalid schedule",
+			args:       []string{"-repo", repo, "-old", "fast", "-new", "slow", "-schedule", "whenever"},
+			wantCode:   2,
+			wantStderr: "schedule",
+		},
+		{
+			name:       "missing old",
+			args:       []string{"-repo", repo, "-new", "slow"},
+			wantCode:   2,
+			wantStderr: "-old is obligatory",
+		},
+		{
+			name:       "missing new",
+			args:       []string{"-repo", repo, "-old", "fast"},
+			wantCode:   2,
+			wantStderr: "-new is obligatory",
+		},
+		{
+			name:       "mod and repo",
+			args:       []string{"-mod", "cool.module", "-repo", repo, "-old", "fast", "-new", "slow"},
+			wantCode:   2,
+			wantStderr: "mutually exclusive",
+		},
+		{
+			name:     "invalid check",
+			args:     []string{"-repo", repo, "-old", "fast", "-new", "slow", "-check", "time/op"},
+			wantCode: 2,
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"-repo", repo, "-old", "fast", "-new", "slow", "-stonks"},
+			wantCode: 2,
+		},
+		{
+			name:       "invalid revision",
+			args:       []string{"-repo", repo, "-old", "fast", "-new", "StoNkS"},
+			wantCode:   3,
+			wantStderr: "failed to run",
+		},
+		{
+			name:       "build failure",
+			args:       []string{"-repo", repo, "-old", "fast", "-new", "broken"},
+			wantCode:   3,
+			wantStderr: "failed to run",
+		},
+		{
+			name:       "check fails",
+			args:       []string{"-repo", repo, "-old", "fast", "-new", "slow", "-check", "time/op=+10%"},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=+10%",
+		},
+		{
+			name:       "time delta shorthand fails",
+			args:       []string{"-repo", repo, "-old", "fast", "-new", "slow", "-time-delta", "+10%"},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=+10%",
+		},
+		{
+			name:     "allocs delta shorthand passes",
+			args:     []string{"-repo", repo, "-old", "fast", "-new", "slow", "-allocs-delta", "+10%", "-alloc-delta", "+10%"},
+			wantCode: 0,
+		},
+		{
+			name:     "check passes",
+			args:     []string{"-repo", repo, "-old", "slow", "-new", "fast", "-check", "time/op=+10%"},
+			wantCode: 0,
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			// Not parallel since concurrent benchmarks would add
+			// noise to each other and the git worktrees may conflict.
+			args := tcase.args
+			if len(args) > 0 {
+				// Keep benchmarks short, the deltas are big enough.
+				args = append([]string{"-benchtime", "20x"}, args...)
+			}
+			code, stderr := runBenchcheck(t, args...)
+			if code != tcase.wantCode {
+				t.Fatalf("benchcheck %v: got exit code %d, want %d\nstderr:\n%s",
+					tcase.args, code, tcase.wantCode, stderr)
+			}
+			if !strings.Contains(stderr, tcase.wantStderr) {
+				t.Fatalf("benchcheck %v: want stderr containing %q, got:\n%s",
+					tcase.args, tcase.wantStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestPackageFailures(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	commitModule(t, repo, "time.Sleep(time.Millisecond)")
+	runGit(t, repo, "tag", "old")
+	writeFile(t, filepath.Join(repo, "broken", "broken_test.go"), `package broken
+
+import "testing"
+
+func BenchmarkBroken(b *testing.B) {
+	panic("boom")
+}
+`)
+
+	type testcase struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string
+		wantStdout string
+	}
+
+	tcases := []testcase{
+		{
+			name:       "abort by default",
+			wantCode:   3,
+			wantStderr: "failed to run",
+		},
+		{
+			name:       "abort",
+			args:       []string{"-on-package-failure", "abort"},
+			wantCode:   3,
+			wantStderr: "failed to run",
+		},
+		{
+			name:       "fail",
+			args:       []string{"-on-package-failure", "fail"},
+			wantCode:   3,
+			wantStderr: "benchmarks failed on package: bench/broken (new)",
+			wantStdout: "Bench",
+		},
+		{
+			name:       "warn",
+			args:       []string{"-on-package-failure", "warn"},
+			wantCode:   0,
+			wantStderr: "panic: boom",
+			wantStdout: "Bench",
+		},
+		{
+			name:       "warn with failed check",
+			args:       []string{"-on-package-failure", "warn", "-check", "time/op<1ns"},
+			wantCode:   1,
+			wantStderr: "check failed: time/op<1ns",
+		},
+		{
+			name:       "failures on report",
+			args:       []string{"-on-package-failure", "warn", "-format", "json"},
+			wantCode:   0,
+			wantStdout: `"package": "bench/broken"`,
+		},
+		{
+			name:       "invalid policy",
+			args:       []string{"-on-package-failure", "ignore"},
+			wantCode:   2,
+			wantStderr: "on-package-failure",
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			args := append([]string{"-repo", repo, "-old", "old", "-new", ".", "-count", "2", "-benchtime", "1x"}, tcase.args...)
+			code, stdout, stderr := runBenchcheckOutput(t, args...)
+			if code != tcase.wantCode {
+				t.Fatalf("benchcheck %v: got exit code %d, want %d\nstderr:\n%s",
+					tcase.args, code, tcase.wantCode, stderr)
+			}
+			if !strings.Contains(stderr, tcase.wantStderr) {
+				t.Fatalf("benchcheck %v: want stderr containing %q, got:\n%s",
+					tcase.args, tcase.wantStderr, stderr)
+			}
+			if !strings.Contains(stdout, tcase.wantStdout) {
+				t.Fatalf("benchcheck %v: want stdout containing %q, got:\n%s",
+					tcase.args, tcase.wantStdout, stdout)
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	commitModule(t, repo, "time.Sleep(time.Millisecond)")
+	runGit(t, repo, "tag", "old")
+
+	args := []string{"-repo", repo, "-old", "old", "-new", ".", "-count", "2", "-benchtime", "1x"}
+
+	code, stderr := runBenchcheck(t, append(args, "-progress")...)
+	if code != 0 {
+		t.Fatalf("benchcheck %v: got exit code %d, want 0\nstderr:\n%s", args, code, stderr)
+	}
+	for _, want := range []string{
+		"resolved old module: ",
+		"resolved new module: " + repo,
+		"building old module: ",
+		"built new module: 1 test binaries",
+		"run 1/4: old module",
+		"run 4/4: new module",
+		"    BenchmarkBench",
+		"compared 3 metrics",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Fatalf("want progress containing %q, got:\n%s", want, stderr)
+		}
+	}
+
+	code, stderr = runBenchcheck(t, args...)
+	if code != 0 {
+		t.Fatalf("benchcheck %v: got exit code %d, want 0\nstderr:\n%s", args, code, stderr)
+	}
+	if strings.Contains(stderr, "run 1/4") {
+		t.Fatalf("want no progress when stderr is not a terminal, got:\n%s", stderr)
+	}
+}
+
+func TestRepoConfig(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	commitModule(t, repo, "time.Sleep(time.Millisecond)")
+	runGit(t, repo, "tag", "fast")
+	commitModule(t, repo, "time.Sleep(5 * time.Millisecond)")
+	runGit(t, repo, "tag", "slow")
+	writeFile(t, filepath.Join(repo, ".benchcheck.yaml"), `
+checks:
+  - time/op=+10%
+`)
+
+	// The test binary runs on another directory, so the configuration
+	// is only found if it is looked up on the -repo directory.
+	args := []string{"-repo", repo, "-old", "fast", "-new", "slow", "-benchtime", "20x"}
+	code, stderr := runBenchcheck(t, args...)
+	if code != 1 {
+		t.Fatalf("benchcheck %v: got exit code %d, want 1\nstderr:\n%s", args, code, stderr)
+	}
+	if want := "check failed: time/op=+10%"; !strings.Contains(stderr, want) {
+		t.Fatalf("benchcheck %v: want stderr containing %q, got:\n%s", args, want, stderr)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupt signals are not supported on windows")
+	}
+
+	type testcase struct {
+		name         string
+		signal       os.Signal
+		slowCheckout bool
+		wantCode     int
+	}
+
+	tcases := []testcase{
+		{name: "SIGINT", signal: os.Interrupt, wantCode: 130},
+		{name: "SIGTERM", signal: syscall.SIGTERM, wantCode: 143},
+		{name: "SIGINT on checkout", signal: os.Interrupt, slowCheckout: true, wantCode: 130},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := t.TempDir()
+			commitModule(t, repo, "time.Sleep(time.Hour)")
+			runGit(t, repo, "tag", "hang")
+			if tcase.slowCheckout {
+				// Hooks run on the process group of git, like the
+				// long checkouts of big repositories.
+				writeFile(t, filepath.Join(repo, ".git", "hooks", "post-checkout"), "#!/bin/sh\nsleep 120\n")
+				if err := os.Chmod(filepath.Join(repo, ".git", "hooks", "post-checkout"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := exec.Command(benchcheckBin, "-repo", repo, "-old", "hang", "-new", "hang")
+			stderr := &strings.Builder{}
+			cmd.Stderr = stderr
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("starting benchcheck: %v", err)
+			}
+
+			// Give it time to build and start running the benchmarks.
+			time.Sleep(3 * time.Second)
+			if err := cmd.Process.Signal(tcase.signal); err != nil {
+				t.Fatalf("interrupting benchcheck: %v", err)
+			}
+
+			done := make(chan error)
+			go func() {
+				done <- cmd.Wait()
+			}()
+
+			select {
+			case <-time.After(time.Minute):
+				_ = cmd.Process.Kill()
+				t.Fatalf("benchcheck still running after interrupt\nstderr:\n%s", stderr)
+			case err := <-done:
+				var exiterr *exec.ExitError
+				if !errors.As(err, &exiterr) || exiterr.ExitCode() != tcase.wantCode {
+					t.Fatalf("got %v, want exit code %d\nstderr:\n%s", err, tcase.wantCode, stderr)
+				}
+			}
+
+			if !strings.Contains(stderr.String(), "interrupted") {
+				t.Fatalf("want stderr containing %q, got:\n%s", "interrupted", stderr)
+			}
+
+			worktrees := exec.Command("git", "worktree", "list")
+			worktrees.Dir = repo
+			out, err := worktrees.Output()
+			if err != nil {
+				t.Fatalf("git worktree list: %v", err)
+			}
+			if lines := strings.Split(strings.TrimSpace(string(out)), "\n"); len(lines) != 1 {
+				t.Fatalf("want temporary worktrees removed after interrupt, got:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	oldfile := filepath.Join(dir, "old.txt")
+	newfile := filepath.Join(dir, "new.txt")
+	writeFile(t, oldfile, `goos: linux
+BenchmarkGobEncode   	100	  13552735 ns/op	  56.63 MB/s
+BenchmarkGobEncode   	100	  13553943 ns/op	  56.63 MB/s
+BenchmarkGobEncode   	100	  13606356 ns/op	  56.41 MB/s
+BenchmarkGobEncode   	100	  13683198 ns/op	  56.09 MB/s
+PASS
+`)
+	writeFile(t, newfile, `goos: linux
+BenchmarkGobEncode   	 100	  11773189 ns/op	  65.19 MB/s
+BenchmarkGobEncode   	 100	  11942588 ns/op	  64.27 MB/s
+BenchmarkGobEncode   	 100	  11786159 ns/op	  65.12 MB/s
+BenchmarkGobEncode   	 100	  11628583 ns/op	  66.00 MB/s
+BenchmarkGobEncode   	 100	  11815924 ns/op	  64.96 MB/s
+PASS
+`)
+	removedfile := filepath.Join(dir, "removed.txt")
+	writeFile(t, removedfile, `goos: linux
+BenchmarkGobDecode   	 100	  11773189 ns/op	  65.19 MB/s
+BenchmarkGobDecode   	 100	  11942588 ns/op	  64.27 MB/s
+BenchmarkGobDecode   	 100	  11786159 ns/op	  65.12 MB/s
+BenchmarkGobDecode   	 100	  11628583 ns/op	  66.00 MB/s
+PASS
+`)
+	configfile := filepath.Join(dir, "benchcheck.yaml")
+	writeFile(t, configfile, `
+checks:
+  - time/op=-10%
+profiles:
+  relaxed:
+    checks:
+      - time/op=+5%
+  flaky:
+    ignore:
+      - GobEncode
+  strict:
+    checks:
+      - time/op=+10%
+    fail_inconclusive: true
+    stat:
+      alpha: 0.001
+`)
+
+	type testcase struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}
+
+	tcases := []testcase{
+		{
+			name:     "no checks",
+			args:     []string{"compare", oldfile, newfile},
+			wantCode: 0,
+		},
+		{
+			name:     "check passes",
+			args:     []string{"compare", "-check", "time/op=+5%", oldfile, newfile},
+			wantCode: 0,
+		},
+		{
+			name:       "check fails",
+			args:       []string{"compare", "-check", "time/op=-10%", oldfile, newfile},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=-10%",
+		},
+		{
+			name:       "checks after files",
+			args:       []string{"compare", oldfile, newfile, "-time-delta", "-10%"},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=-10%",
+		},
+		{
+			name:     "most specific check passes",
+			args:     []string{"compare", "-check", "time/op=-10%", "-check", "BenchmarkGob.*:time/op=-20%", oldfile, newfile},
+			wantCode: 0,
+		},
+		{
+			name:       "benchmark check fails",
+			args:       []string{"compare", "-check", "time/op=-20%", "-check", "BenchmarkGob.*:time/op=-10%", oldfile, newfile},
+			wantCode:   1,
+			wantStderr: "check failed: BenchmarkGob.*:time/op=-10%",
+		},
+		{
+			name:     "absolute bound passes",
+			args:     []string{"compare", "-check", "time/op<12ms", oldfile, newfile},
+			wantCode: 0,
+		},
+		{
+			name:       "absolute bound fails",
+			args:       []string{"compare", "-check", "time/op<=11ms", oldfile, newfile},
+			wantCode:   1,
+			wantStderr: "violates <=11ms",
+		},
+		{
+			name:       "absolute delta fails",
+			args:       []string{"compare", "-time-delta", "-1ms", oldfile, newfile},
+			wantCode:   1,
+			wantStderr: "exceeds -1ms",
+		},
+		{
+			name:       "time delta without unit is percent",
+			args:       []string{"compare", "-time-delta", "-10", oldfile, newfile},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=-10",
+		},
+		{
+			name:       "absolute bound with invalid unit",
+			args:       []string{"compare", "-check", "time/op<1KB", oldfile, newfile},
+			wantCode:   2,
+			wantStderr: "invalid unit",
+		},
+		{
+			name:       "reversed files",
+			args:       []string{"compare", newfile, oldfile, "-check", "time/op=+10%"},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=+10%",
+		},
+		{
+			name:     "stricter alpha",
+			args:     []string{"compare", "-alpha", "0.001", newfile, oldfile, "-check", "time/op=+10%"},
+			wantCode: 0,
+		},
+		{
+			name:       "t-test",
+			args:       []string{"compare", "-stat-test", "ttest", newfile, oldfile, "-check", "time/op=+10%"},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=+10%",
+		},
+		{
+			name:       "inconclusive",
+			args:       []string{"compare", "-alpha", "0.001", "-require-significance", newfile, oldfile, "-check", "time/op=+10%"},
+			wantCode:   0,
+			wantStderr: "check inconclusive: time/op=+10%",
+		},
+		{
+			name:       "fail inconclusive",
+			args:       []string{"compare", "-alpha", "0.001", "-fail-inconclusive", newfile, oldfile, "-check", "time/op=+10%"},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=+10%",
+		},
+		{
+			name:       "invalid alpha",
+			args:       []string{"compare", "-alpha", "2", oldfile, newfile},
+			wantCode:   2,
+			wantStderr: "alpha",
+		},
+		{
+			name:       "invalid stat test",
+			args:       []string{"compare", "-stat-test", "StoNkS", oldfile, newfile},
+			wantCode:   2,
+			wantStderr: "invalid stat test",
+		},
+		{
+			name:       "config check fails",
+			args:       []string{"compare", "-config", configfile, oldfile, newfile},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=-10%",
+		},
+		{
+			name:     "config profile check passes",
+			args:     []string{"compare", "-config", configfile, "-profile", "relaxed", oldfile, newfile},
+			wantCode: 0,
+		},
+		{
+			name:     "config profile ignores benchmark",
+			args:     []string{"compare", "-config", configfile, "-profile", "flaky", oldfile, newfile},
+			wantCode: 0,
+		},
+		{
+			name:       "config and flag checks",
+			args:       []string{"compare", "-config", configfile, "-profile", "relaxed", "-check", "time/op=-10%", oldfile, newfile},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=-10%",
+		},
+		{
+			name:       "config fail inconclusive with require significance",
+			args:       []string{"compare", "-config", configfile, "-profile", "strict", "-require-significance", newfile, oldfile},
+			wantCode:   1,
+			wantStderr: "check failed: time/op=+10%",
+		},
+		{
+			name:       "config unknown profile",
+			args:       []string{"compare", "-config", configfile, "-profile", "stonks", oldfile, newfile},
+			wantCode:   2,
+			wantStderr: "stonks",
+		},
+		{
+			name:       "profile without config",
+			args:       []string{"compare", "-profile", "relaxed", oldfile, newfile},
+			wantCode:   2,
+			wantStderr: "configuration file",
+		},
+		{
+			name:       "nonexistent config",
+			args:       []string{"compare", "-config", filepath.Join(dir, "nonexistent.yaml"), oldfile, newfile},
+			wantCode:   2,
+			wantStderr: "nonexistent",
+		},
+		{
+			name:       "removed benchmarks are reported",
+			args:       []string{"compare", oldfile, removedfile},
+			wantCode:   0,
+			wantStderr: "warning: removed benchmarks: GobEncode",
+		},
+		{
+			name:       "added benchmarks are reported",
+			args:       []string{"compare", oldfile, removedfile},
+			wantCode:   0,
+			wantStderr: "added benchmarks: GobDecode",
+		},
+		{
+			name:       "fail removed",
+			args:       []string{"compare", "-fail-removed", oldfile, removedfile},
+			wantCode:   1,
+			wantStderr: "check failed: removed benchmarks: GobEncode",
+		},
+		{
+			name:     "fail removed with no removed benchmarks",
+			args:     []string{"compare", "-fail-removed", oldfile, newfile},
+			wantCode: 0,
+		},
+		{
+			name:       "missing new file",
+			args:       []string{"compare", oldfile},
+			wantCode:   2,
+			wantStderr: "want old and new files",
+		},
+		{
+			name:       "too many files",
+			args:       []string{"compare", oldfile, newfile, newfile},
+			wantCode:   2,
+			wantStderr: "want old and new files",
+		},
+		{
+			name:       "nonexistent file",
+			args:       []string{"compare", oldfile, filepath.Join(dir, "nonexistent")},
+			wantCode:   2,
+			wantStderr: "nonexistent",
+		},
+		{
+			name:     "invalid check",
+			args:     []string{"compare", "-check", "time/op", oldfile, newfile},
+			wantCode: 2,
+		},
+		{
+			name:       "invalid format",
+			args:       []string{"compare", "-format", "xml", oldfile, newfile},
+			wantCode:   2,
+			wantStderr: "unknown format",
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, stderr := runBenchcheck(t, tcase.args...)
+			if code != tcase.wantCode {
+				t.Fatalf("benchcheck %v: got exit code %d, want %d\nstderr:\n%s",
+					tcase.args, code, tcase.wantCode, stderr)
+			}
+			if !strings.Contains(stderr, tcase.wantStderr) {
+				t.Fatalf("benchcheck %v: want stderr containing %q, got:\n%s",
+					tcase.args, tcase.wantStderr, stderr)
+			}
+		})
+	}
+}
+
+func TestCompareJSON(t *testing.T) {
+	t.Parallel()
+
+	oldfile := filepath.Join("..", "..", "testdata", "old.txt")
+	newfile := filepath.Join("..", "..", "testdata", "new.txt")
+
+	code, stdout, stderr := runBenchcheckOutput(t, "compare", "-format", "json",
+		"-check", "time/op=-10%", "-check", "speed=+20%", oldfile, newfile)
+	if code != 1 {
+		t.Fatalf("got exit code %d, want 1\nstderr:\n%s", code, stderr)
+	}
+
+	report := struct {
+		Version int `json:"version"`
+		Results []struct {
+			Metric     string `json:"metric"`
+			BenchDiffs []struct {
+				Name string `json:"name"`
+			} `json:"bench_diffs"`
+		} `json:"results"`
+		Checks []struct {
+			Checker    string `json:"checker"`
+			Passed     bool   `json:"passed"`
+			Violations []struct {
+				Name string `json:"name"`
+			} `json:"violations"`
+		} `json:"checks"`
+	}{}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("stdout is not valid json: %v\n%s", err, stdout)
+	}
+
+	if report.Version != 1 {
+		t.Fatalf("got version %d, want 1", report.Version)
+	}
+	if len(report.Results) != 2 || len(report.Results[0].BenchDiffs) != 2 {
+		t.Fatalf("want 2 metrics with 2 benchmarks, got: %s", stdout)
+	}
+	if len(report.Checks) != 2 {
+		t.Fatalf("want 2 checks, got: %s", stdout)
+	}
+
+	timeCheck := report.Checks[0]
+	if timeCheck.Checker != "time/op=-10%" || timeCheck.Passed {
+		t.Fatalf("want failed time/op check, got: %+v", timeCheck)
+	}
+	if len(timeCheck.Violations) != 1 || timeCheck.Violations[0].Name != "GobEncode" {
+		t.Fatalf("want GobEncode violation, got: %+v", timeCheck.Violations)
+	}
+	if !report.Checks[1].Passed {
+		t.Fatalf("want speed check to pass, got: %+v", report.Checks[1])
+	}
+}
+
+func TestCompareJSONFailRemoved(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	oldfile := filepath.Join("..", "..", "testdata", "old.txt")
+	newfile := filepath.Join(dir, "removed.txt")
+	writeFile(t, newfile, `goos: linux
+BenchmarkGobDecode   	 100	  11773189 ns/op
+BenchmarkGobDecode   	 100	  11942588 ns/op
+PASS
+`)
+
+	code, stdout, stderr := runBenchcheckOutput(t, "compare", "-format", "json",
+		"-fail-removed", oldfile, newfile)
+	if code != 1 {
+		t.Fatalf("got exit code %d, want 1\nstderr:\n%s", code, stderr)
+	}
+
+	report := struct {
+		FailRemoved bool `json:"fail_removed"`
+		Passed      bool `json:"passed"`
+	}{}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("stdout is not valid json: %v\n%s", err, stdout)
+	}
+	if !report.FailRemoved || report.Passed {
+		t.Fatalf("want report failed by removed benchmarks, got: %s", stdout)
+	}
+}
+
+func TestCompareMarkdownOutput(t *testing.T) {
+	t.Parallel()
+
+	// Reversed files, so there are regressions.
+	oldfile := filepath.Join("..", "..", "testdata", "new.txt")
+	newfile := filepath.Join("..", "..", "testdata", "old.txt")
+	outfile := filepath.Join(t.TempDir(), "report.md")
+
+	code, stdout, stderr := runBenchcheckOutput(t, "compare", "-format", "markdown",
+		"-output", outfile, "-time-delta", "+10%", oldfile, newfile)
+	if code != 1 {
+		t.Fatalf("got exit code %d, want 1\nstderr:\n%s", code, stderr)
+	}
+	if stdout != "" {
+		t.Fatalf("want no stdout when using -output, got:\n%s", stdout)
+	}
+
+	report, err := os.ReadFile(outfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"### time/op",
+		"| 🔴 **GobEncode** |",
+		"🔴 **time/op=+10%** failed",
+	} {
+		if !strings.Contains(string(report), want) {
+			t.Fatalf("want report containing %q, got:\n%s", want, report)
+		}
+	}
+}
+
+func TestCompareJUnitOutput(t *testing.T) {
+	t.Parallel()
+
+	oldfile := filepath.Join("..", "..", "testdata", "old.txt")
+	newfile := filepath.Join("..", "..", "testdata", "new.txt")
+
+	code, stdout, stderr := runBenchcheckOutput(t, "compare", "-format", "junit",
+		"-time-delta", "-10%", oldfile, newfile)
+	if code != 1 {
+		t.Fatalf("got exit code %d, want 1\nstderr:\n%s", code, stderr)
+	}
+
+	report := struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+	}{}
+	if err := xml.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("stdout is not valid xml: %v\n%s", err, stdout)
+	}
+	if report.Failures != 1 {
+		t.Fatalf("want 1 failure, got %d:\n%s", report.Failures, stdout)
+	}
+}
+
+func runBenchcheck(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+
+	code, _, stderr := runBenchcheckOutput(t, args...)
+	return code, stderr
+}
+
+func runBenchcheckOutput(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	cmd := exec.Command(benchcheckBin, args...)
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err == nil {
+		return 0, stdout.String(), stderr.String()
+	}
+
+	var exiterr *exec.ExitError
+	if !errors.As(err, &exiterr) {
+		t.Fatalf("running benchcheck %v: %v", args, err)
+	}
+	return exiterr.ExitCode(), stdout.String(), stderr.String()
+}
+
+// commitModule commits a module with a single benchmark running
+// the given code on the git repository at dir.
+func commitModule(t *testing.T, dir string, benchcode string) {
+	t.Helper()
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module bench\n\ngo 1.16\n")
+	writeFile(t, filepath.Join(dir, "bench_test.go"), fmt.Sprintf(`package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkBench(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		%s
+	}
+}
+`, benchcode))
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		runGit(t, dir, "init", "-q")
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", benchcode)
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=benchcheck",
+		"GIT_AUTHOR_EMAIL=benchcheck@test",
+		"GIT_COMMITTER_NAME=benchcheck",
+		"GIT_COMMITTER_EMAIL=benchcheck@test",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/madlambda/benchcheck"
+)
+
+// compareCmd is the subcommand that compares saved benchmark results.
+const compareCmd = "compare"
+
+// compare compares two files with saved "go test -bench" outputs,
+// performing checks on them without running any benchmarks.
+func compare(args []string) {
+	fs := flag.NewFlagSet(compareCmd, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: benchcheck %s [flags] <old file> <new file>\n", compareCmd)
+		fs.PrintDefaults()
+	}
+
+	checks := &checkFlags{}
+	addCheckFlags(fs, checks)
+	statFlags := addStatFlags(fs)
+	out := addOutputFlags(fs)
+	conf := addConfigFlags(fs)
+
+	files := parseInterspersed(fs, args)
+	if len(files) != 2 {
+		usageError(fs, fmt.Sprintf("want old and new files, got: %v", files))
+	}
+	config, err := conf.load(".")
+	if err != nil {
+		usageError(fs, err.Error())
+	}
+	statOpts := statOptions(config.Stat, statFlags, setFlags(fs))
+	if err := statOpts.Validate(); err != nil {
+		usageError(fs, err.Error())
+	}
+
+	oldf, err := os.Open(files[0])
+	if err != nil {
+		usageError(fs, err.Error())
+	}
+	defer oldf.Close()
+
+	newf, err := os.Open(files[1])
+	if err != nil {
+		usageError(fs, err.Error())
+	}
+	defer newf.Close()
+
+	results, err := benchcheck.StatFiles(oldf, newf, benchcheck.WithStatOptions(statOpts))
+	if err != nil {
+		runError(err)
+	}
+
+	os.Exit(checkResults(results, nil, checks, config, out))
+}
+
+// parseInterspersed parses the flags, allowing flags and positional
+// arguments to be mixed, returning the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	positional := []string{}
+	for {
+		// Errors are handled by the flag set using flag.ExitOnError.
+		_ = fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
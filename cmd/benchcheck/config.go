@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/madlambda/benchcheck"
+)
+
+// configFlags are the flags selecting the configuration file.
+type configFlags struct {
+	path    *string
+	profile *string
+}
+
+// addConfigFlags adds the -config and -profile flags to the flag set.
+func addConfigFlags(fs *flag.FlagSet) configFlags {
+	return configFlags{
+		path: fs.String("config", "", fmt.Sprintf(
+			"configuration file with checks and options, by default the first found on the -repo directory of: %s",
+			strings.Join(benchcheck.DefaultConfigFiles, ", "))),
+		profile: fs.String("profile", "", "profile of the configuration file to be used, like quick or release"),
+	}
+}
+
+// load loads the configuration file, if there is any, looking for the
+// default files on dir when no -config is given. Flags explicitly
+// provided on the command line take precedence over the configuration.
+func (c configFlags) load(dir string) (benchcheck.Config, error) {
+	path := *c.path
+	if path == "" {
+		found, ok := benchcheck.FindConfig(dir)
+		if !ok {
+			if *c.profile != "" {
+				return benchcheck.Config{}, errors.New("-profile requires a configuration file")
+			}
+			return benchcheck.Config{}, nil
+		}
+		path = found
+	}
+	return benchcheck.LoadConfig(path, *c.profile)
+}
+
+// setFlags returns the names of the flags explicitly provided.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
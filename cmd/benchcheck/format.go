@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/madlambda/benchcheck"
+)
+
+// formatter writes a report on a specific output format.
+type formatter func(io.Writer, benchcheck.Report) error
+
+var formatters = map[string]formatter{
+	"text":     formatText,
+	"json":     formatJSON,
+	"markdown": benchcheck.WriteMarkdown,
+	"junit":    benchcheck.WriteJUnit,
+}
+
+// formatFlag is a flag selecting the output format.
+type formatFlag struct {
+	name   string
+	format formatter
+}
+
+func (f *formatFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.name
+}
+
+func (f *formatFlag) Set(val string) error {
+	format, ok := formatters[val]
+	if !ok {
+		return fmt.Errorf("unknown format %q", val)
+	}
+	f.name = val
+	f.format = format
+	return nil
+}
+
+// output is where and how the report is written.
+type output struct {
+	format *formatFlag
+	path   *string
+}
+
+// addOutputFlags adds the -format and -output flags to the flag set.
+func addOutputFlags(fs *flag.FlagSet) output {
+	format := &formatFlag{name: "text", format: formatText}
+	fs.Var(format, "format", "output format: text, json, markdown or junit")
+	path := fs.String("output", "", "file where the report is written, instead of stdout")
+	return output{format: format, path: path}
+}
+
+// write writes the report on the output.
+func (o output) write(report benchcheck.Report) error {
+	if *o.path == "" {
+		return o.format.format(os.Stdout, report)
+	}
+
+	f, err := os.Create(*o.path)
+	if err != nil {
+		return err
+	}
+	if err := o.format.format(f, report); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func formatText(w io.Writer, report benchcheck.Report) error {
+	for _, result := range report.Results {
+		if _, err := fmt.Fprintf(w, "metric: %s\n", result.Metric); err != nil {
+			return err
+		}
+		// Benchmarks are grouped by package, which is shown like
+		// benchstat does when there is more than one.
+		withPackages := len(result.Packages()) > 1
+		pkg := ""
+		for _, diff := range result.BenchDiffs {
+			if withPackages && diff.Package != "" && diff.Package != pkg {
+				pkg = diff.Package
+				if _, err := fmt.Fprintf(w, "pkg: %s\n", pkg); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(w, diff); err != nil {
+				return err
+			}
+		}
+		for _, name := range result.Removed {
+			if _, err := fmt.Fprintf(w, "removed: %s\n", name); err != nil {
+				return err
+			}
+		}
+		for _, name := range result.Added {
+			if _, err := fmt.Fprintf(w, "added: %s\n", name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatJSON(w io.Writer, report benchcheck.Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
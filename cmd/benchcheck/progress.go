@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/madlambda/benchcheck"
+)
+
+// addProgressFlag adds the -progress flag to the flag set. By default
+// progress is shown only when stderr is a terminal, so CI logs are
+// not cluttered unless explicitly requested.
+func addProgressFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("progress", isTerminal(os.Stderr),
+		"show the progress of building and running the benchmarks on stderr (default true if stderr is a terminal)")
+}
+
+// progress renders progress events as they happen.
+type progress struct {
+	w io.Writer
+}
+
+func (p progress) render(event benchcheck.Event) {
+	switch event.Kind {
+	case benchcheck.ModuleResolved:
+		fmt.Fprintf(p.w, "resolved %s module: %s\n", event.Side, event.Module.Path())
+	case benchcheck.BuildStarted:
+		fmt.Fprintf(p.w, "building %s module: %s\n", event.Side, event.Module.Path())
+	case benchcheck.BuildFinished:
+		fmt.Fprintf(p.w, "built %s module: %d test binaries\n", event.Side, len(event.Packages))
+	case benchcheck.RunStarted:
+		fmt.Fprintf(p.w, "run %d/%d: %s module\n", event.Seq+1, event.Runs, event.Side)
+	case benchcheck.BenchParsed:
+		fmt.Fprintf(p.w, "    %s\n", strings.Join(strings.Fields(event.Result), " "))
+	case benchcheck.StatsComputed:
+		fmt.Fprintf(p.w, "compared %d metrics\n", len(event.Stats))
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
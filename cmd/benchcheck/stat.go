@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/madlambda/benchcheck"
+)
+
+// statTestFlag is a flag selecting the statistical test.
+type statTestFlag benchcheck.StatTest
+
+func (s *statTestFlag) String() string {
+	if s == nil {
+		return ""
+	}
+	return benchcheck.StatTest(*s).String()
+}
+
+func (s *statTestFlag) Set(val string) error {
+	test, err := benchcheck.ParseStatTest(val)
+	if err != nil {
+		return err
+	}
+	*s = statTestFlag(test)
+	return nil
+}
+
+// addStatFlags adds the flags configuring how results are compared to
+// the flag set. The returned options are only set after parsing.
+func addStatFlags(fs *flag.FlagSet) *benchcheck.StatOptions {
+	opts := &benchcheck.StatOptions{}
+	fs.Var((*statTestFlag)(&opts.Test), "stat-test", fmt.Sprintf(
+		"statistical test used to compare samples: %s (Mann-Whitney U-test), %s (Welch's t-test) or %s",
+		benchcheck.UTest, benchcheck.TTest, benchcheck.NoTest))
+	fs.Float64Var(&opts.Alpha, "alpha", benchcheck.DefaultAlpha,
+		"significance level, differences with a greater p-value are not significant")
+	fs.BoolVar(&opts.GeoMean, "geomean", false, "add the geometric mean of all benchmarks of each metric")
+	return opts
+}
+
+// statOptions returns the configured stat options with the
+// ones explicitly provided by flags taking precedence.
+func statOptions(config benchcheck.StatOptions, flags *benchcheck.StatOptions, set map[string]bool) benchcheck.StatOptions {
+	if set["stat-test"] {
+		config.Test = flags.Test
+	}
+	if set["alpha"] {
+		config.Alpha = flags.Alpha
+	}
+	if set["geomean"] {
+		config.GeoMean = flags.GeoMean
+	}
+	return config
+}
@@ -0,0 +1,147 @@
+package benchcheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// CanceledError is returned when a command is interrupted because
+// its context was canceled or its deadline exceeded.
+// The whole process group of the command is killed, so no
+// orphaned "go test" or test binary processes are left behind.
+type CanceledError struct {
+	// Cmd is the interrupted command.
+	Cmd *exec.Cmd
+	// Err is the error of the context, context.Canceled
+	// or context.DeadlineExceeded.
+	Err error
+}
+
+// Error returns the string representation of the error.
+func (c *CanceledError) Error() string {
+	return fmt.Sprintf("canceled running: %v on dir %s: %v", c.Cmd, c.Cmd.Dir, c.Err)
+}
+
+// Unwrap returns the error of the context, so errors.Is can check
+// for context.Canceled or context.DeadlineExceeded.
+func (c *CanceledError) Unwrap() error {
+	return c.Err
+}
+
+// command creates a command that is interrupted, with its whole
+// process group, when the given context is done.
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	return cmd
+}
+
+// combinedOutput runs a command created with command, returning its
+// combined stdout and stderr. Failures are returned as a *CmdError, or
+// as a *CanceledError if the command context was done.
+func combinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	out := &bytes.Buffer{}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err := runCommand(ctx, cmd, nil)
+	return out.Bytes(), cmdError(ctx, cmd, err, out.String())
+}
+
+// streamOutput works like combinedOutput, but each line of the output
+// is given to onLine as soon as the command writes it, instead of
+// only after the command finishes. onLine is called on the calling
+// goroutine.
+func streamOutput(ctx context.Context, cmd *exec.Cmd, onLine func(string)) ([]byte, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating output pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	out := &bytes.Buffer{}
+	err = runCommand(ctx, cmd, func() {
+		// Only the command and its children must hold the write
+		// end of the pipe, so reading ends when all of them exit.
+		_ = w.Close()
+
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			out.WriteString(line)
+			if line != "" {
+				onLine(strings.TrimRight(line, "\r\n"))
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+	return out.Bytes(), cmdError(ctx, cmd, err, out.String())
+}
+
+// output runs a command created with command, returning its stdout.
+// Failures are returned as a *CmdError with the stdout and stderr as
+// output, or as a *CanceledError if the command context was done.
+func output(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := runCommand(ctx, cmd, nil)
+	return stdout.Bytes(), cmdError(ctx, cmd, err, stdout.String()+stderr.String())
+}
+
+// runCommand runs the command, killing its whole process group when the
+// context is done. Killing only the command process, like exec.CommandContext
+// does, would leave its children running, like the test binaries of "go test".
+// If whileRunning is not nil it is called after the command starts, before
+// waiting for it to finish.
+func runCommand(ctx context.Context, cmd *exec.Cmd, whileRunning func()) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	killed := make(chan struct{})
+	go func() {
+		defer close(killed)
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	if whileRunning != nil {
+		whileRunning()
+	}
+	err := cmd.Wait()
+	close(done)
+	<-killed
+	return err
+}
+
+// cmdError returns the error of a command that failed as a *CmdError,
+// or as a *CanceledError if the command context is done.
+func cmdError(ctx context.Context, cmd *exec.Cmd, err error, output string) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return &CanceledError{Cmd: cmd, Err: ctxErr}
+	}
+	return &CmdError{
+		Cmd:    cmd,
+		Err:    err,
+		Output: output,
+	}
+}
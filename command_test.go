@@ -0,0 +1,107 @@
+package benchcheck_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/madlambda/benchcheck"
+)
+
+func TestRunBenchContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	mod := hangingModule(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := benchcheck.RunBenchContext(ctx, mod)
+	assertCanceled(t, err, context.DeadlineExceeded, start)
+}
+
+func TestStatModulesContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	mod := hangingModule(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := benchcheck.StatModulesContext(ctx, mod, mod)
+	assertCanceled(t, err, context.DeadlineExceeded, start)
+}
+
+func TestGetModuleContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	_, err := benchcheck.GetModuleContext(ctx, "github.com/madlambda/jtoh", "v0.1.0")
+	assertCanceled(t, err, context.Canceled, start)
+
+	_, err = benchcheck.StatModuleContext(ctx, "github.com/madlambda/jtoh", "v0.1.0", "v0.2.0")
+	assertCanceled(t, err, context.Canceled, start)
+}
+
+func TestGitRevisionContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+	writeFile(t, filepath.Join(repo, "file"), "v1")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "-q", "-m", "v1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	_, err := benchcheck.GitRevisionContext(ctx, repo, "HEAD")
+	assertCanceled(t, err, context.Canceled, start)
+}
+
+// hangingModule creates a module with a benchmark that runs for a long
+// time on a child process of the test binary, like benchmarks running
+// external commands do, so killing only "go test" is not enough.
+func hangingModule(t *testing.T) benchcheck.Module {
+	t.Helper()
+
+	mod := benchModule(t, map[string]string{
+		"bench_test.go": `package bench
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func BenchmarkHang(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = exec.Command("sleep", "120").Run()
+	}
+}
+`,
+	})
+	return mod
+}
+
+func assertCanceled(t *testing.T, err error, want error, start time.Time) {
+	t.Helper()
+
+	var canceled *benchcheck.CanceledError
+	if !errors.As(err, &canceled) {
+		t.Fatalf("want *benchcheck.CanceledError, got: %v", err)
+	}
+	if !errors.Is(err, want) {
+		t.Fatalf("want error wrapping %v, got: %v", want, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Fatalf("canceled commands took %v to return, processes were not killed", elapsed)
+	}
+}
@@ -0,0 +1,440 @@
+package benchcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat is the format of a configuration file.
+type ConfigFormat string
+
+const (
+	// ConfigYAML is the YAML configuration format.
+	ConfigYAML ConfigFormat = "yaml"
+	// ConfigTOML is the TOML configuration format.
+	ConfigTOML ConfigFormat = "toml"
+	// ConfigJSON is the JSON configuration format.
+	ConfigJSON ConfigFormat = "json"
+)
+
+// DefaultConfigFiles are the names of the configuration files
+// looked up when no configuration file is explicitly provided.
+var DefaultConfigFiles = []string{
+	".benchcheck.yaml",
+	".benchcheck.yml",
+	".benchcheck.toml",
+	".benchcheck.json",
+}
+
+// Config is a benchcheck configuration, usually loaded from a file with
+// LoadConfig, so the same checks and options don't need to be repeated
+// on every CI job.
+//
+// A configuration file has the checks, per benchmark checks, ignored
+// benchmarks and run and stat options. Named profiles can override any
+// of them, replacing the values from the top level of the file.
+// A YAML configuration looks like:
+//
+//	checks:
+//	  - time/op=+10%
+//	  - allocs/op<=2
+//	benchmarks:
+//	  BenchmarkEncode.*:
+//	    - time/op=+2%
+//	ignore:
+//	  - BenchmarkFlaky
+//	run:
+//	  count: 10
+//	  benchtime: 1s
+//	stat:
+//	  alpha: 0.01
+//	profiles:
+//	  quick:
+//	    run:
+//	      count: 3
+//	      benchtime: 100x
+type Config struct {
+	// Checkers are all the checkers, including per benchmark ones.
+	Checkers []Checker
+	// Ignore has the regular expressions of the benchmarks that are
+	// ignored, matched like go test -bench does. See Config.Filter.
+	Ignore []*BenchRegexp
+	// Run are the options used to run the benchmarks.
+	Run RunOptions
+	// Schedule is the schedule of the benchmark runs.
+	Schedule Schedule
+	// Stat are the options used to compare the benchmarks.
+	Stat StatOptions
+	// FailRemoved is true if removed benchmarks should fail the checks,
+	// see RemovedBenchmarks.
+	FailRemoved bool
+}
+
+// configFile is the schema of configuration files.
+type configFile struct {
+	configProfile `yaml:",inline"`
+	Profiles      map[string]configProfile `json:"profiles" yaml:"profiles" toml:"profiles"`
+}
+
+// configProfile has all configurations that can be set on the top
+// level of a configuration file or on a profile. Fields that are not
+// set are nil, so profiles only override what they set.
+type configProfile struct {
+	Checks              []string            `json:"checks" yaml:"checks" toml:"checks"`
+	Benchmarks          map[string][]string `json:"benchmarks" yaml:"benchmarks" toml:"benchmarks"`
+	Ignore              []string            `json:"ignore" yaml:"ignore" toml:"ignore"`
+	RequireSignificance *bool               `json:"require_significance" yaml:"require_significance" toml:"require_significance"`
+	FailInconclusive    *bool               `json:"fail_inconclusive" yaml:"fail_inconclusive" toml:"fail_inconclusive"`
+	FailRemoved         *bool               `json:"fail_removed" yaml:"fail_removed" toml:"fail_removed"`
+	Run                 configRun           `json:"run" yaml:"run" toml:"run"`
+	Stat                configStat          `json:"stat" yaml:"stat" toml:"stat"`
+}
+
+type configRun struct {
+	Bench      *string  `json:"bench" yaml:"bench" toml:"bench"`
+	Run        *string  `json:"run" yaml:"run" toml:"run"`
+	Packages   []string `json:"packages" yaml:"packages" toml:"packages"`
+	Tags       []string `json:"tags" yaml:"tags" toml:"tags"`
+	Count      *int     `json:"count" yaml:"count" toml:"count"`
+	BenchTime  *string  `json:"benchtime" yaml:"benchtime" toml:"benchtime"`
+	CPU        []int    `json:"cpu" yaml:"cpu" toml:"cpu"`
+	Timeout    *string  `json:"timeout" yaml:"timeout" toml:"timeout"`
+	NoBenchMem *bool    `json:"no_benchmem" yaml:"no_benchmem" toml:"no_benchmem"`
+	Schedule   *string  `json:"schedule" yaml:"schedule" toml:"schedule"`
+}
+
+type configStat struct {
+	Test    *string  `json:"test" yaml:"test" toml:"test"`
+	Alpha   *float64 `json:"alpha" yaml:"alpha" toml:"alpha"`
+	GeoMean *bool    `json:"geomean" yaml:"geomean" toml:"geomean"`
+}
+
+// FindConfig looks for one of the DefaultConfigFiles on the given
+// directory, returning its path and true if found.
+func FindConfig(dir string) (string, bool) {
+	for _, name := range DefaultConfigFiles {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// LoadConfig loads the configuration file at the given path, using the
+// given profile. If the profile is empty only the top level of the file
+// is used. The format is detected by the file extension, which must be
+// one of .yaml, .yml, .toml or .json.
+func LoadConfig(path string, profile string) (Config, error) {
+	var format ConfigFormat
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		format = ConfigYAML
+	case ".toml":
+		format = ConfigTOML
+	case ".json":
+		format = ConfigJSON
+	default:
+		return Config{}, fmt.Errorf("loading config %q: unknown format, want .yaml, .yml, .toml or .json", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("loading config: %v", err)
+	}
+	defer f.Close()
+
+	cfg, err := ParseConfig(f, format, profile)
+	if err != nil {
+		return Config{}, fmt.Errorf("loading config %q: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses a configuration on the given format from the reader,
+// using the given profile. If the profile is empty only the top level of
+// the configuration is used.
+func ParseConfig(r io.Reader, format ConfigFormat, profile string) (Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config: %v", err)
+	}
+
+	file := configFile{}
+	switch format {
+	case ConfigYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && err != io.EOF {
+			return Config{}, fmt.Errorf("parsing yaml config: %v", err)
+		}
+	case ConfigTOML:
+		md, err := toml.Decode(string(data), &file)
+		if err != nil {
+			return Config{}, fmt.Errorf("parsing toml config: %v", err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return Config{}, fmt.Errorf("parsing toml config: unknown field %q", undecoded[0])
+		}
+	case ConfigJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return Config{}, fmt.Errorf("parsing json config: %v", err)
+		}
+	default:
+		return Config{}, fmt.Errorf("invalid config format %q", format)
+	}
+
+	conf := file.configProfile
+	if profile != "" {
+		override, ok := file.Profiles[profile]
+		if !ok {
+			return Config{}, fmt.Errorf("unknown profile %q, available profiles: %s",
+				profile, strings.Join(file.profileNames(), ", "))
+		}
+		conf = conf.override(override)
+	}
+	return conf.config()
+}
+
+func (f configFile) profileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// override returns the configuration with all fields set on the
+// given profile replacing the ones of the configuration.
+func (p configProfile) override(o configProfile) configProfile {
+	if o.Checks != nil {
+		p.Checks = o.Checks
+	}
+	if o.Benchmarks != nil {
+		p.Benchmarks = o.Benchmarks
+	}
+	if o.Ignore != nil {
+		p.Ignore = o.Ignore
+	}
+	if o.RequireSignificance != nil {
+		p.RequireSignificance = o.RequireSignificance
+	}
+	if o.FailInconclusive != nil {
+		p.FailInconclusive = o.FailInconclusive
+	}
+	if o.FailRemoved != nil {
+		p.FailRemoved = o.FailRemoved
+	}
+
+	if o.Run.Bench != nil {
+		p.Run.Bench = o.Run.Bench
+	}
+	if o.Run.Run != nil {
+		p.Run.Run = o.Run.Run
+	}
+	if o.Run.Packages != nil {
+		p.Run.Packages = o.Run.Packages
+	}
+	if o.Run.Tags != nil {
+		p.Run.Tags = o.Run.Tags
+	}
+	if o.Run.Count != nil {
+		p.Run.Count = o.Run.Count
+	}
+	if o.Run.BenchTime != nil {
+		p.Run.BenchTime = o.Run.BenchTime
+	}
+	if o.Run.CPU != nil {
+		p.Run.CPU = o.Run.CPU
+	}
+	if o.Run.Timeout != nil {
+		p.Run.Timeout = o.Run.Timeout
+	}
+	if o.Run.NoBenchMem != nil {
+		p.Run.NoBenchMem = o.Run.NoBenchMem
+	}
+	if o.Run.Schedule != nil {
+		p.Run.Schedule = o.Run.Schedule
+	}
+
+	if o.Stat.Test != nil {
+		p.Stat.Test = o.Stat.Test
+	}
+	if o.Stat.Alpha != nil {
+		p.Stat.Alpha = o.Stat.Alpha
+	}
+	if o.Stat.GeoMean != nil {
+		p.Stat.GeoMean = o.Stat.GeoMean
+	}
+	return p
+}
+
+// config validates the configuration, converting it to a Config.
+func (p configProfile) config() (Config, error) {
+	cfg := Config{}
+
+	checks := append([]string{}, p.Checks...)
+	patterns := make([]string, 0, len(p.Benchmarks))
+	for pattern := range p.Benchmarks {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		for _, check := range p.Benchmarks[pattern] {
+			checks = append(checks, pattern+":"+check)
+		}
+	}
+
+	requireSignificance := p.RequireSignificance != nil && *p.RequireSignificance
+	failInconclusive := p.FailInconclusive != nil && *p.FailInconclusive
+	for _, check := range checks {
+		checker, err := ParseChecker(check)
+		if err != nil {
+			return Config{}, err
+		}
+		if requireSignificance || failInconclusive {
+			checker = checker.RequireSignificance(failInconclusive)
+		}
+		cfg.Checkers = append(cfg.Checkers, checker)
+	}
+
+	cfg.FailRemoved = p.FailRemoved != nil && *p.FailRemoved
+
+	for _, ignore := range p.Ignore {
+		re, err := CompileBenchRegexp(ignore)
+		if err != nil {
+			return Config{}, fmt.Errorf("parsing ignored benchmark %q: %v", ignore, err)
+		}
+		cfg.Ignore = append(cfg.Ignore, re)
+	}
+
+	run := p.Run
+	if run.Bench != nil {
+		cfg.Run.Bench = *run.Bench
+	}
+	if run.Run != nil {
+		cfg.Run.Run = *run.Run
+	}
+	cfg.Run.Packages = run.Packages
+	cfg.Run.Tags = run.Tags
+	if run.Count != nil {
+		cfg.Run.Count = *run.Count
+	}
+	if run.BenchTime != nil {
+		cfg.Run.BenchTime = *run.BenchTime
+	}
+	cfg.Run.CPU = run.CPU
+	if run.Timeout != nil {
+		timeout, err := time.ParseDuration(*run.Timeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("parsing run timeout: %v", err)
+		}
+		cfg.Run.Timeout = timeout
+	}
+	if run.NoBenchMem != nil {
+		cfg.Run.NoBenchMem = *run.NoBenchMem
+	}
+	if err := cfg.Run.Validate(); err != nil {
+		return Config{}, err
+	}
+	if run.Schedule != nil {
+		schedule, err := ParseSchedule(*run.Schedule)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Schedule = schedule
+	}
+
+	stat := p.Stat
+	if stat.Test != nil {
+		test, err := ParseStatTest(*stat.Test)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Stat.Test = test
+	}
+	if stat.Alpha != nil {
+		cfg.Stat.Alpha = *stat.Alpha
+	}
+	if stat.GeoMean != nil {
+		cfg.Stat.GeoMean = *stat.GeoMean
+	}
+	if err := cfg.Stat.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Options returns the options to run and compare benchmarks
+// with StatModule and StatModules.
+func (c Config) Options() []Option {
+	return []Option{
+		WithRunOptions(c.Run),
+		WithSchedule(c.Schedule),
+		WithStatOptions(c.Stat),
+	}
+}
+
+// Ignored returns true if the benchmark with the given name, as
+// reported on results, is ignored. The ignored regular expressions
+// are matched like Checker.Matches does, see BenchRegexp.
+func (c Config) Ignored(name string) bool {
+	for _, re := range c.Ignore {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the results without the ignored benchmarks, including
+// added and removed ones. Metrics left with no benchmarks are removed.
+func (c Config) Filter(results []StatResult) []StatResult {
+	if len(c.Ignore) == 0 {
+		return results
+	}
+	filtered := []StatResult{}
+	for _, result := range results {
+		diffs := []BenchDiff{}
+		for _, diff := range result.BenchDiffs {
+			if !c.Ignored(diff.Name) {
+				diffs = append(diffs, diff)
+			}
+		}
+		added := c.filterNames(result.Added)
+		removed := c.filterNames(result.Removed)
+		if len(diffs) > 0 || len(added) > 0 || len(removed) > 0 {
+			filtered = append(filtered, StatResult{
+				Metric:     result.Metric,
+				BenchDiffs: diffs,
+				Added:      added,
+				Removed:    removed,
+			})
+		}
+	}
+	return filtered
+}
+
+func (c Config) filterNames(names []BenchName) []BenchName {
+	var filtered []BenchName
+	for _, name := range names {
+		if !c.Ignored(name.Name) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,389 @@
+package benchcheck_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+const yamlConfig = `
+checks:
+  - time/op=+10%
+  - allocs/op<=2
+benchmarks:
+  BenchmarkEncode.*:
+    - time/op=+2%
+ignore:
+  - BenchmarkFlaky
+run:
+  bench: Encode
+  packages: [./encoding/...]
+  count: 10
+  timeout: 5m
+stat:
+  alpha: 0.01
+profiles:
+  quick:
+    run:
+      count: 3
+      benchtime: 100x
+      schedule: sequential
+  release:
+    checks:
+      - time/op=+5%
+    fail_inconclusive: true
+    fail_removed: true
+    stat:
+      test: ttest
+      geomean: true
+`
+
+const tomlConfig = `
+checks = ["time/op=+10%", "allocs/op<=2"]
+ignore = ["BenchmarkFlaky"]
+
+[benchmarks]
+"BenchmarkEncode.*" = ["time/op=+2%"]
+
+[run]
+bench = "Encode"
+packages = ["./encoding/..."]
+count = 10
+timeout = "5m"
+
+[stat]
+alpha = 0.01
+
+[profiles.quick.run]
+count = 3
+benchtime = "100x"
+schedule = "sequential"
+
+[profiles.release]
+checks = ["time/op=+5%"]
+fail_inconclusive = true
+fail_removed = true
+
+[profiles.release.stat]
+test = "ttest"
+geomean = true
+`
+
+const jsonConfig = `{
+  "checks": ["time/op=+10%", "allocs/op<=2"],
+  "benchmarks": {"BenchmarkEncode.*": ["time/op=+2%"]},
+  "ignore": ["BenchmarkFlaky"],
+  "run": {"bench": "Encode", "packages": ["./encoding/..."], "count": 10, "timeout": "5m"},
+  "stat": {"alpha": 0.01},
+  "profiles": {
+    "quick": {"run": {"count": 3, "benchtime": "100x", "schedule": "sequential"}},
+    "release": {
+      "checks": ["time/op=+5%"],
+      "fail_inconclusive": true,
+      "fail_removed": true,
+      "stat": {"test": "ttest", "geomean": true}
+    }
+  }
+}`
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	type want struct {
+		checks      []string
+		run         benchcheck.RunOptions
+		failRemoved bool
+		schedule    benchcheck.Schedule
+		stat        benchcheck.StatOptions
+	}
+
+	type testcase struct {
+		profile string
+		want    want
+	}
+
+	baseRun := benchcheck.RunOptions{
+		Bench:    "Encode",
+		Packages: []string{"./encoding/..."},
+		Count:    10,
+		Timeout:  5 * time.Minute,
+	}
+	quickRun := baseRun
+	quickRun.Count = 3
+	quickRun.BenchTime = "100x"
+
+	tcases := []testcase{
+		{
+			want: want{
+				checks: []string{"time/op=+10%", "allocs/op<=2", "BenchmarkEncode.*:time/op=+2%"},
+				run:    baseRun,
+				stat:   benchcheck.StatOptions{Alpha: 0.01},
+			},
+		},
+		{
+			profile: "quick",
+			want: want{
+				checks:   []string{"time/op=+10%", "allocs/op<=2", "BenchmarkEncode.*:time/op=+2%"},
+				run:      quickRun,
+				schedule: benchcheck.Sequential,
+				stat:     benchcheck.StatOptions{Alpha: 0.01},
+			},
+		},
+		{
+			profile: "release",
+			want: want{
+				checks:      []string{"time/op=+5%", "BenchmarkEncode.*:time/op=+2%"},
+				run:         baseRun,
+				failRemoved: true,
+				stat: benchcheck.StatOptions{
+					Test:    benchcheck.TTest,
+					Alpha:   0.01,
+					GeoMean: true,
+				},
+			},
+		},
+	}
+
+	formats := map[benchcheck.ConfigFormat]string{
+		benchcheck.ConfigYAML: yamlConfig,
+		benchcheck.ConfigTOML: tomlConfig,
+		benchcheck.ConfigJSON: jsonConfig,
+	}
+
+	for format, config := range formats {
+		for _, tc := range tcases {
+			format, config, tcase := format, config, tc
+			t.Run(string(format)+"/"+tcase.profile, func(t *testing.T) {
+				t.Parallel()
+
+				cfg, err := benchcheck.ParseConfig(strings.NewReader(config), format, tcase.profile)
+				assert.NoError(t, err)
+
+				checks := []string{}
+				for _, checker := range cfg.Checkers {
+					checks = append(checks, checker.String())
+				}
+				if diff := cmp.Diff(tcase.want.checks, checks); diff != "" {
+					t.Fatalf("checks: %s", diff)
+				}
+				if diff := cmp.Diff(tcase.want.run, cfg.Run); diff != "" {
+					t.Fatalf("run options: %s", diff)
+				}
+				if cfg.Schedule != tcase.want.schedule {
+					t.Fatalf("got schedule %v; want %v", cfg.Schedule, tcase.want.schedule)
+				}
+				if diff := cmp.Diff(tcase.want.stat, cfg.Stat); diff != "" {
+					t.Fatalf("stat options: %s", diff)
+				}
+				if cfg.FailRemoved != tcase.want.failRemoved {
+					t.Fatalf("got fail removed %t; want %t", cfg.FailRemoved, tcase.want.failRemoved)
+				}
+				if !cfg.Ignored("Flaky-8") || cfg.Ignored("EncodeJSON-8") {
+					t.Fatalf("want only BenchmarkFlaky ignored, got: %v", cfg.Ignore)
+				}
+			})
+		}
+	}
+}
+
+func TestParseConfigSignificance(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := benchcheck.ParseConfig(strings.NewReader(yamlConfig), benchcheck.ConfigYAML, "release")
+	assert.NoError(t, err)
+
+	// Without significance the non significant delta would pass.
+	result := benchcheck.StatResult{
+		Metric: benchcheck.TimeMetric,
+		BenchDiffs: []benchcheck.BenchDiff{
+			{
+				Name:     "Decode",
+				OldStats: benchcheck.BenchStats{Mean: 100, Samples: 5},
+				NewStats: benchcheck.BenchStats{Mean: 200, Samples: 5},
+				PValue:   0.5,
+			},
+		},
+	}
+	report := benchcheck.NewReport([]benchcheck.StatResult{result}, cfg.Checkers)
+	if report.Passed() {
+		t.Fatalf("want inconclusive benchmark to fail on release profile, got: %v", report.Checks)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name    string
+		format  benchcheck.ConfigFormat
+		config  string
+		profile string
+	}
+
+	tcases := []testcase{
+		{
+			name:    "unknown profile",
+			format:  benchcheck.ConfigYAML,
+			config:  yamlConfig,
+			profile: "stonks",
+		},
+		{
+			name:   "unknown yaml field",
+			format: benchcheck.ConfigYAML,
+			config: "chekcs: [time/op=+10%]",
+		},
+		{
+			name:   "unknown toml field",
+			format: benchcheck.ConfigTOML,
+			config: `chekcs = ["time/op=+10%"]`,
+		},
+		{
+			name:   "unknown json field",
+			format: benchcheck.ConfigJSON,
+			config: `{"chekcs": ["time/op=+10%"]}`,
+		},
+		{
+			name:   "invalid check",
+			format: benchcheck.ConfigYAML,
+			config: "checks: [time/op]",
+		},
+		{
+			name:   "invalid benchmark check",
+			format: benchcheck.ConfigYAML,
+			config: "benchmarks: {BenchmarkEncode: [time/op<1KB]}",
+		},
+		{
+			name:   "invalid ignore",
+			format: benchcheck.ConfigYAML,
+			config: "ignore: ['Benchmark(']",
+		},
+		{
+			name:   "invalid timeout",
+			format: benchcheck.ConfigYAML,
+			config: "run: {timeout: forever}",
+		},
+		{
+			name:   "invalid count",
+			format: benchcheck.ConfigYAML,
+			config: "run: {count: -1}",
+		},
+		{
+			name:   "invalid schedule",
+			format: benchcheck.ConfigYAML,
+			config: "run: {schedule: whenever}",
+		},
+		{
+			name:   "invalid stat test",
+			format: benchcheck.ConfigYAML,
+			config: "stat: {test: stonks}",
+		},
+		{
+			name:   "invalid alpha",
+			format: benchcheck.ConfigYAML,
+			config: "stat: {alpha: 2}",
+		},
+		{
+			name:   "invalid format",
+			format: benchcheck.ConfigFormat("xml"),
+			config: "<checks/>",
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := benchcheck.ParseConfig(strings.NewReader(tcase.config), tcase.format, tcase.profile)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	_, found := benchcheck.FindConfig(dir)
+	if found {
+		t.Fatalf("want no config found on empty dir %q", dir)
+	}
+
+	path := filepath.Join(dir, ".benchcheck.toml")
+	writeFile(t, path, tomlConfig)
+
+	foundPath, found := benchcheck.FindConfig(dir)
+	if !found || foundPath != path {
+		t.Fatalf("FindConfig(%q)=(%q, %t); want (%q, true)", dir, foundPath, found, path)
+	}
+
+	cfg, err := benchcheck.LoadConfig(path, "quick")
+	assert.NoError(t, err)
+	assert.EqualInts(t, 3, cfg.Run.Count)
+
+	_, err = benchcheck.LoadConfig(filepath.Join(dir, "missing.yaml"), "")
+	assert.Error(t, err)
+
+	unknown := filepath.Join(dir, "config.ini")
+	writeFile(t, unknown, "checks=time/op=+10%")
+	_, err = benchcheck.LoadConfig(unknown, "")
+	assert.Error(t, err)
+}
+
+func TestConfigFilter(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := benchcheck.ParseConfig(strings.NewReader("ignore: [Flaky, '^BenchmarkSetup$']"), benchcheck.ConfigYAML, "")
+	assert.NoError(t, err)
+
+	results := []benchcheck.StatResult{
+		{
+			Metric: benchcheck.TimeMetric,
+			BenchDiffs: []benchcheck.BenchDiff{
+				{Name: "Encode"},
+				{Name: "Flaky-8"},
+				{Name: "Setup-8"},
+				{Name: "SetupMore-8"},
+			},
+		},
+		{
+			Metric: benchcheck.AllocsMetric,
+			BenchDiffs: []benchcheck.BenchDiff{
+				{Name: "VeryFlaky"},
+			},
+		},
+		{
+			Metric:  benchcheck.AllocMetric,
+			Added:   []benchcheck.BenchName{{Package: "example.com/flaky", Name: "Flaky"}, {Package: "example.com/encoding", Name: "Encode"}},
+			Removed: []benchcheck.BenchName{{Name: "Setup"}},
+		},
+		{
+			Metric:  benchcheck.SpeedMetric,
+			Removed: []benchcheck.BenchName{{Name: "Setup-4"}},
+		},
+	}
+
+	want := []benchcheck.StatResult{
+		{
+			Metric: benchcheck.TimeMetric,
+			BenchDiffs: []benchcheck.BenchDiff{
+				{Name: "Encode"},
+				{Name: "SetupMore-8"},
+			},
+		},
+		{
+			Metric:     benchcheck.AllocMetric,
+			BenchDiffs: []benchcheck.BenchDiff{},
+			Added:      []benchcheck.BenchName{{Package: "example.com/encoding", Name: "Encode"}},
+		},
+	}
+	if diff := cmp.Diff(want, cfg.Filter(results)); diff != "" {
+		t.Fatalf("filtered results: %s", diff)
+	}
+}
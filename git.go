@@ -0,0 +1,67 @@
+package benchcheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitRevision will checkout a specific revision (commit, branch or tag)
+// of the local git repository at repo on a temporary git worktree and
+// return the module found there. This doesn't rely on the Go module
+// proxy, so any revision available locally can be used.
+//
+// If repo is a subdirectory of the git repository the returned module
+// will point to the same subdirectory inside the worktree.
+//
+// The returned module must be closed after use so the temporary
+// worktree is removed.
+//
+// Any errors running "git" can be inspected in detail by
+// checking if the returned error is a *CmdError.
+func GitRevision(repo string, rev string) (Module, error) {
+	return GitRevisionContext(context.Background(), repo, rev)
+}
+
+// GitRevisionContext works like GitRevision, but "git" is killed if the
+// context is done before it finishes, returning a *CanceledError.
+// The temporary worktree is removed in that case too.
+func GitRevisionContext(ctx context.Context, repo string, rev string) (Module, error) {
+	prefix, err := git(ctx, repo, "rev-parse", "--show-prefix")
+	if err != nil {
+		return Module{}, err
+	}
+
+	worktree, err := os.MkdirTemp("", "benchcheck-")
+	if err != nil {
+		return Module{}, fmt.Errorf("creating worktree dir: %v", err)
+	}
+
+	if _, err := git(ctx, repo, "worktree", "add", "--detach", worktree, rev); err != nil {
+		_ = os.RemoveAll(worktree)
+		// An interrupted "git worktree add" may leave the worktree
+		// registered, so it is pruned now that its dir is gone.
+		_, _ = git(context.Background(), repo, "worktree", "prune")
+		return Module{}, err
+	}
+
+	return Module{
+		path: filepath.Join(worktree, strings.TrimSpace(prefix)),
+		cleanup: func() error {
+			if _, err := git(context.Background(), repo, "worktree", "remove", "--force", worktree); err != nil {
+				return err
+			}
+			return os.RemoveAll(worktree)
+		},
+	}, nil
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := command(ctx, "git", args...)
+	cmd.Dir = dir
+
+	out, err := combinedOutput(ctx, cmd)
+	return string(out), err
+}
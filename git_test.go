@@ -0,0 +1,137 @@
+package benchcheck_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestGitRevision(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+	writeFile(t, filepath.Join(repo, "sub", "file"), "v1")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "-q", "-m", "v1")
+	runGit(t, repo, "tag", "v1")
+	writeFile(t, filepath.Join(repo, "sub", "file"), "v2")
+	runGit(t, repo, "commit", "-q", "-a", "-m", "v2")
+
+	tests := []struct {
+		desc     string
+		repo     string
+		rev      string
+		file     string
+		wantErr  bool
+		wantData string
+	}{
+		{
+			desc:     "Tag",
+			repo:     repo,
+			rev:      "v1",
+			file:     "sub/file",
+			wantData: "v1",
+		},
+		{
+			desc:     "Head",
+			repo:     repo,
+			rev:      "HEAD",
+			file:     "sub/file",
+			wantData: "v2",
+		},
+		{
+			desc:     "RelativeRevision",
+			repo:     repo,
+			rev:      "HEAD~1",
+			file:     "sub/file",
+			wantData: "v1",
+		},
+		{
+			desc:     "SubdirOfRepo",
+			repo:     filepath.Join(repo, "sub"),
+			rev:      "v1",
+			file:     "file",
+			wantData: "v1",
+		},
+		{
+			desc:    "InvalidRevision",
+			repo:    repo,
+			rev:     "StoNkS",
+			wantErr: true,
+		},
+		{
+			desc:    "NotARepo",
+			repo:    t.TempDir(),
+			rev:     "HEAD",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		test := tc
+		t.Run(test.desc, func(t *testing.T) {
+			// Not parallel since concurrent worktree changes
+			// on the same repo may conflict on the git lock.
+			mod, err := benchcheck.GitRevision(test.repo, test.rev)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assertNoError(t, err, "benchcheck.GitRevision(%q, %q)", test.repo, test.rev)
+
+			data, err := os.ReadFile(filepath.Join(mod.Path(), test.file))
+			assert.NoError(t, err)
+			assert.EqualStrings(t, test.wantData, string(data))
+
+			assertNoError(t, mod.Close(), "closing %v", mod)
+
+			if _, err := os.Stat(mod.Path()); !os.IsNotExist(err) {
+				t.Fatalf("want %q removed after close, got: %v", mod.Path(), err)
+			}
+		})
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=benchcheck",
+		"GIT_AUTHOR_EMAIL=benchcheck@test",
+		"GIT_COMMITTER_NAME=benchcheck",
+		"GIT_COMMITTER_EMAIL=benchcheck@test",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+// benchModule creates a local module named "bench" with the given
+// files, keyed by their slash separated path on the module.
+func benchModule(t *testing.T, files map[string]string) benchcheck.Module {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module bench\n\ngo 1.16\n")
+	for path, data := range files {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(path)), data)
+	}
+	mod, err := benchcheck.LocalModule(dir)
+	assertNoError(t, err)
+	return mod
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	t.Helper()
+
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0600))
+}
@@ -3,7 +3,9 @@ module github.com/madlambda/benchcheck
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/google/go-cmp v0.5.7
 	github.com/madlambda/spells v0.3.0
 	golang.org/x/perf v0.0.0-20220411212318-84e58bfe0a7e
+	gopkg.in/yaml.v3 v3.0.1
 )
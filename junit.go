@@ -0,0 +1,196 @@
+package benchcheck
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the report as JUnit XML, so CI systems can show
+// regressions as failed tests. Each checker is a test suite where each
+// benchmark of the checker metric is a test case, failing if the
+// benchmark violated the checker. Benchmarks are only reported by the
+// most specific checkers matching them, like in NewReport, and benchmarks
+// with no checker are reported as test suites with skipped test cases.
+// When Report.FailRemoved is true removed benchmarks are reported as
+// failed test cases of their own test suite.
+func WriteJUnit(w io.Writer, report Report) error {
+	suites := junitTestSuites{Name: "benchcheck"}
+
+	checkers := make([]Checker, len(report.Checks))
+	for i, check := range report.Checks {
+		checkers[i] = check.Checker
+	}
+	selected := newSelection(checkers, report.Results)
+
+	for i, check := range report.Checks {
+		suites.add(newJUnitCheckSuite(check, selected, i, report.Results))
+	}
+
+	if removed := newJUnitRemovedSuite(report.Results); report.FailRemoved && removed.Tests > 0 {
+		suites.add(removed)
+	}
+
+	for _, result := range report.Results {
+		suite := junitTestSuite{Name: result.Metric}
+		for _, diff := range result.BenchDiffs {
+			if selected.checked(result.Metric, diff.Name) {
+				continue
+			}
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      diff.Name,
+				ClassName: junitClassName(diff.Package, result.Metric),
+				Skipped:   &junitSkipped{Message: "no checker for metric " + result.Metric},
+				SystemOut: diff.String(),
+			})
+			suite.Tests++
+			suite.Skipped++
+		}
+		if suite.Tests > 0 {
+			suites.add(suite)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return fmt.Errorf("encoding junit report: %v", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newJUnitCheckSuite(check CheckReport, selected selection, index int, results []StatResult) junitTestSuite {
+	suite := junitTestSuite{Name: check.Checker.String()}
+
+	violations := map[string]Violation{}
+	for _, v := range check.Violations {
+		violations[v.fullName()] = v
+	}
+	inconclusive := map[string]Violation{}
+	for _, v := range check.Inconclusive {
+		inconclusive[v.fullName()] = v
+	}
+
+	for _, result := range results {
+		if result.Metric != check.Metric {
+			continue
+		}
+		for _, diff := range result.BenchDiffs {
+			// Benchmarks checked by more specific checkers are
+			// only reported on the test suites of those checkers.
+			if !selected.checks(index, result.Metric, diff.Name) {
+				continue
+			}
+			testcase := junitTestCase{
+				Name:      diff.Name,
+				ClassName: junitClassName(diff.Package, check.Metric),
+				SystemOut: diff.String(),
+			}
+			if v, ok := violations[diff.fullName()]; ok {
+				testcase.Failure = &junitFailure{
+					Message: fmt.Sprintf(
+						"old %s: new %s: %s",
+						strings.TrimSpace(v.Old), strings.TrimSpace(v.New), v.describe(),
+					),
+					Type: "regression",
+					Text: v.String(),
+				}
+				suite.Failures++
+			} else if v, ok := inconclusive[diff.fullName()]; ok {
+				message := fmt.Sprintf(
+					"old %s: new %s: %s (%s)",
+					strings.TrimSpace(v.Old), strings.TrimSpace(v.New), v.describe(), formatPValue(v.PValue),
+				)
+				if check.Checker.failInconclusive {
+					testcase.Failure = &junitFailure{
+						Message: message,
+						Type:    string(CheckInconclusive),
+						Text:    v.String(),
+					}
+					suite.Failures++
+				} else {
+					testcase.Skipped = &junitSkipped{Message: "inconclusive: " + message}
+					suite.Skipped++
+				}
+			}
+			suite.TestCases = append(suite.TestCases, testcase)
+			suite.Tests++
+		}
+	}
+
+	return suite
+}
+
+func newJUnitRemovedSuite(results []StatResult) junitTestSuite {
+	suite := junitTestSuite{Name: removedCheck}
+	for _, name := range RemovedBenchmarks(results) {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      name.Name,
+			ClassName: junitClassName(name.Package, removedCheck),
+			Failure: &junitFailure{
+				Message: "benchmark only exists on the old results",
+				Type:    "removed",
+			},
+		})
+		suite.Tests++
+		suite.Failures++
+	}
+	return suite
+}
+
+// junitClassName returns the package of a benchmark as the class name
+// of its test case, like go test reports, or the fallback for benchmarks
+// without package.
+func junitClassName(pkg, fallback string) string {
+	if pkg == "" {
+		return fallback
+	}
+	return pkg
+}
+
+func (s *junitTestSuites) add(suite junitTestSuite) {
+	s.Suites = append(s.Suites, suite)
+	s.Tests += suite.Tests
+	s.Failures += suite.Failures
+	s.Skipped += suite.Skipped
+}
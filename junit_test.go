@@ -0,0 +1,103 @@
+package benchcheck_test
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	t.Parallel()
+
+	// Reversing old/new so we have regressions.
+	results := statTestdataFiles(t, "new.txt", "old.txt")
+
+	checkers := []benchcheck.Checker{}
+	for _, c := range []string{"time/op=+5%", "time/op=+20%"} {
+		checker, err := benchcheck.ParseChecker(c)
+		assert.NoError(t, err)
+		checkers = append(checkers, checker)
+	}
+
+	got := &strings.Builder{}
+	err := benchcheck.WriteJUnit(got, benchcheck.NewReport(results, checkers))
+	assert.NoError(t, err)
+
+	assertGolden(t, filepath.Join("testdata", "report.junit.xml"), got.String())
+
+	parsed := struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Skipped  int `xml:"skipped,attr"`
+	}{}
+	assert.NoError(t, xml.Unmarshal([]byte(got.String()), &parsed))
+
+	// 2 checkers on time/op with 2 benchmarks each + speed with no checkers.
+	assert.EqualInts(t, 6, parsed.Tests)
+	assert.EqualInts(t, 1, parsed.Failures)
+	assert.EqualInts(t, 2, parsed.Skipped)
+}
+
+func TestWriteJUnitInconclusive(t *testing.T) {
+	t.Parallel()
+
+	// Reversing old/new so we have regressions.
+	results := statTestdataFiles(t, "new.txt", "old.txt")
+
+	checker, err := benchcheck.ParseChecker("time/op=+1%")
+	assert.NoError(t, err)
+
+	for _, failInconclusive := range []bool{false, true} {
+		checkers := []benchcheck.Checker{checker.RequireSignificance(failInconclusive)}
+
+		got := &strings.Builder{}
+		err := benchcheck.WriteJUnit(got, benchcheck.NewReport(results, checkers))
+		assert.NoError(t, err)
+
+		parsed := struct {
+			Failures int `xml:"failures,attr"`
+			Skipped  int `xml:"skipped,attr"`
+		}{}
+		assert.NoError(t, xml.Unmarshal([]byte(got.String()), &parsed))
+
+		// GobEncode is a significant regression while JSONEncode is
+		// inconclusive, plus 2 skipped speed benchmarks with no checkers.
+		if failInconclusive {
+			assert.EqualInts(t, 2, parsed.Failures)
+			assert.EqualInts(t, 2, parsed.Skipped)
+		} else {
+			assert.EqualInts(t, 1, parsed.Failures)
+			assert.EqualInts(t, 3, parsed.Skipped)
+		}
+		if !strings.Contains(got.String(), "not significant") {
+			t.Fatalf("want inconclusive benchmark on report, got:\n%s", got)
+		}
+	}
+}
+
+func TestWriteJUnitRemoved(t *testing.T) {
+	t.Parallel()
+
+	report := benchcheck.NewReport([]benchcheck.StatResult{
+		{
+			Metric:  benchcheck.TimeMetric,
+			Removed: []benchcheck.BenchName{{Package: "example.com/json", Name: "Encode"}},
+		},
+	}, nil)
+	report.FailRemoved = true
+
+	got := &strings.Builder{}
+	assert.NoError(t, benchcheck.WriteJUnit(got, report))
+
+	want := `<testcase name="Encode" classname="example.com/json">`
+	if !strings.Contains(got.String(), want) {
+		t.Fatalf("want %q on report, got:\n%s", want, got)
+	}
+	if !strings.Contains(got.String(), `type="removed"`) {
+		t.Fatalf("want removed benchmark failure on report, got:\n%s", got)
+	}
+}
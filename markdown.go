@@ -0,0 +1,198 @@
+package benchcheck
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RegressionSymbol is the symbol used to highlight regressions on reports.
+const RegressionSymbol = "🔴"
+
+// InconclusiveSymbol is the symbol used to highlight inconclusive checks on reports.
+const InconclusiveSymbol = "⚠️"
+
+// WriteMarkdown writes the report as markdown, suitable for comments
+// on pull requests. There is one table per metric with one row per
+// benchmark, regressions are highlighted with RegressionSymbol and
+// bold formatting, and when a metric has benchmarks from more than one
+// package the table has a package column. Packages whose benchmarks
+// failed and benchmarks that were removed or added are listed after the
+// tables, and a summary of failed checks, including removed benchmarks
+// when Report.FailRemoved is true, is added at the end.
+func WriteMarkdown(w io.Writer, report Report) error {
+	md := &strings.Builder{}
+
+	md.WriteString("## Benchmarks\n")
+
+	if len(report.Results) == 0 {
+		md.WriteString("\nNo benchmarks to compare.\n")
+	}
+
+	for _, result := range report.Results {
+		if len(result.BenchDiffs) == 0 {
+			continue
+		}
+		withPackages := len(result.Packages()) > 1
+		fmt.Fprintf(md, "\n### %s\n\n", escapeMarkdown(result.Metric))
+		if withPackages {
+			md.WriteString("| Package | Benchmark | Old | New | Delta |\n")
+			md.WriteString("|:--|:--|--:|--:|--:|\n")
+		} else {
+			md.WriteString("| Benchmark | Old | New | Delta |\n")
+			md.WriteString("|:--|--:|--:|--:|\n")
+		}
+
+		for _, diff := range result.BenchDiffs {
+			cols := []string{
+				escapeMarkdown(diff.Name),
+				escapeMarkdown(diff.Old),
+				escapeMarkdown(diff.New),
+				formatDelta(diff.Delta),
+			}
+			if isRegression(result.Metric, diff.Delta) {
+				for i, col := range cols {
+					cols[i] = "**" + col + "**"
+				}
+				cols[0] = RegressionSymbol + " " + cols[0]
+			}
+			if withPackages {
+				cols = append([]string{escapeMarkdown(diff.Package)}, cols...)
+			}
+			fmt.Fprintf(md, "| %s |\n", strings.Join(cols, " | "))
+		}
+	}
+
+	failed := make([]string, len(report.Failures))
+	for i, failure := range report.Failures {
+		failed[i] = failure.String()
+	}
+	writeMarkdownList(md, "Failed packages", failed)
+	writeMarkdownList(md, "Removed benchmarks", benchNames(RemovedBenchmarks(report.Results)))
+	writeMarkdownList(md, "Added benchmarks", benchNames(AddedBenchmarks(report.Results)))
+
+	if len(report.Checks) > 0 || report.FailRemoved {
+		md.WriteString("\n### Checks\n\n")
+		writeMarkdownChecks(md, report)
+	}
+
+	_, err := io.WriteString(w, md.String())
+	return err
+}
+
+func writeMarkdownList(md *strings.Builder, title string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintf(md, "\n### %s\n\n", title)
+	for _, name := range names {
+		fmt.Fprintf(md, "- %s\n", escapeMarkdown(name))
+	}
+}
+
+// benchNames returns the package qualified names of the benchmarks.
+func benchNames(benchs []BenchName) []string {
+	names := make([]string, len(benchs))
+	for i, bench := range benchs {
+		names[i] = bench.String()
+	}
+	return names
+}
+
+func writeMarkdownChecks(md *strings.Builder, report Report) {
+	reported := 0
+	for _, check := range report.Checks {
+		status := check.Status()
+		if status == CheckPassed {
+			continue
+		}
+		if reported > 0 {
+			md.WriteString("\n")
+		}
+		reported++
+
+		symbol := RegressionSymbol
+		if status == CheckInconclusive {
+			symbol = InconclusiveSymbol
+		}
+		fmt.Fprintf(md, "%s **%s** %s:\n\n",
+			symbol, escapeMarkdown(check.Checker.String()), status)
+		for _, v := range check.Violations {
+			fmt.Fprintf(md, "- %s: %s → %s, %s\n",
+				escapeMarkdown(v.Name),
+				escapeMarkdown(v.Old),
+				escapeMarkdown(v.New),
+				escapeMarkdown(v.describe()),
+			)
+		}
+		for _, v := range check.Inconclusive {
+			fmt.Fprintf(md, "- %s: %s → %s, %s (%s)\n",
+				escapeMarkdown(v.Name),
+				escapeMarkdown(v.Old),
+				escapeMarkdown(v.New),
+				escapeMarkdown(v.describe()),
+				formatPValue(v.PValue),
+			)
+		}
+	}
+
+	checks := len(report.Checks)
+	if report.FailRemoved {
+		checks++
+	}
+	if report.removedFailed() {
+		if reported > 0 {
+			md.WriteString("\n")
+		}
+		reported++
+
+		fmt.Fprintf(md, "%s **%s** %s:\n\n", RegressionSymbol, removedCheck, CheckFailed)
+		for _, name := range RemovedBenchmarks(report.Results) {
+			fmt.Fprintf(md, "- %s\n", escapeMarkdown(name.String()))
+		}
+	}
+
+	if reported == 0 {
+		fmt.Fprintf(md, "All %d checks passed.\n", checks)
+	}
+}
+
+// isRegression returns true if the delta means a performance
+// regression on the given metric. For all metrics lower is better,
+// except for speed.
+func isRegression(metric string, delta float64) bool {
+	if metric == SpeedMetric {
+		return delta < 0
+	}
+	return delta > 0
+}
+
+// formatDelta formats the delta like benchstat does, using
+// "~" to indicate that there is no significant change.
+func formatDelta(delta float64) string {
+	if delta == 0 {
+		return "~"
+	}
+	return fmt.Sprintf("%+.2f%%", delta)
+}
+
+// formatPValue formats the p-value like benchstat does, a negative
+// p-value means the statistical test could not be performed, which
+// usually happens when there are too few samples.
+func formatPValue(pval float64) string {
+	if pval < 0 {
+		return "no p-value"
+	}
+	return fmt.Sprintf("p=%0.3f", pval)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"|", `\|`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(strings.TrimSpace(s))
+}
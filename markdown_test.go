@@ -0,0 +1,177 @@
+package benchcheck_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files on testdata")
+
+func TestWriteMarkdown(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name                string
+		checks              []string
+		requireSignificance bool
+		golden              string
+	}
+
+	tcases := []testcase{
+		{
+			name:   "no checks",
+			golden: "report_nochecks.md",
+		},
+		{
+			name:   "passed checks",
+			checks: []string{"time/op=+20%", "speed=-20%"},
+			golden: "report_passed.md",
+		},
+		{
+			name:   "failed checks",
+			checks: []string{"time/op=+5%", "speed=-5%", "allocs/op=+5%"},
+			golden: "report_failed.md",
+		},
+		{
+			name:   "absolute checks",
+			checks: []string{"time/op<=40ms", "speed>=60MB/s", "time/op=+1ms"},
+			golden: "report_absolute.md",
+		},
+		{
+			name:                "inconclusive checks",
+			checks:              []string{"time/op=+1%", "speed=-20%"},
+			requireSignificance: true,
+			golden:              "report_inconclusive.md",
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Reversing old/new so we have regressions.
+			results := statTestdataFiles(t, "new.txt", "old.txt")
+
+			checkers := []benchcheck.Checker{}
+			for _, c := range tcase.checks {
+				checker, err := benchcheck.ParseChecker(c)
+				assert.NoError(t, err)
+				if tcase.requireSignificance {
+					checker = checker.RequireSignificance(false)
+				}
+				checkers = append(checkers, checker)
+			}
+
+			got := &strings.Builder{}
+			err := benchcheck.WriteMarkdown(got, benchcheck.NewReport(results, checkers))
+			assert.NoError(t, err)
+
+			assertGolden(t, filepath.Join("testdata", tcase.golden), got.String())
+		})
+	}
+}
+
+func TestWriteMarkdownNoResults(t *testing.T) {
+	t.Parallel()
+
+	got := &strings.Builder{}
+	err := benchcheck.WriteMarkdown(got, benchcheck.NewReport(nil, nil))
+	assert.NoError(t, err)
+
+	if !strings.Contains(got.String(), "No benchmarks to compare") {
+		t.Fatalf("want no benchmarks message, got:\n%s", got)
+	}
+}
+
+func TestWriteMarkdownAddedRemoved(t *testing.T) {
+	t.Parallel()
+
+	results, err := benchcheck.Stat(
+		benchcheck.BenchResults{
+			"BenchmarkEncode  	 50	  31735022 ns/op",
+			"BenchmarkEncode  	 50	  31735022 ns/op",
+			"BenchmarkDecode  	 50	  31735022 ns/op",
+		},
+		benchcheck.BenchResults{
+			"BenchmarkEncode  	 50	  31735022 ns/op",
+			"BenchmarkEncode  	 50	  31735022 ns/op",
+			"BenchmarkDecodeFast  	 50	  31735022 ns/op",
+		},
+	)
+	assert.NoError(t, err)
+
+	report := benchcheck.NewReport(results, nil)
+	report.Failures = []benchcheck.PackageFailure{
+		{Side: benchcheck.NewSide, Package: "example.com/broken"},
+	}
+
+	got := &strings.Builder{}
+	err = benchcheck.WriteMarkdown(got, report)
+	assert.NoError(t, err)
+
+	for _, want := range []string{
+		"| Encode |",
+		"### Failed packages\n\n- example.com/broken (new)\n",
+		"### Removed benchmarks\n\n- Decode\n",
+		"### Added benchmarks\n\n- DecodeFast\n",
+	} {
+		if !strings.Contains(got.String(), want) {
+			t.Fatalf("want %q on report, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteMarkdownPackages(t *testing.T) {
+	t.Parallel()
+
+	results := benchcheck.BenchResults{
+		"pkg: example.com/a",
+		"BenchmarkEncode  	 50	  31735022 ns/op",
+		"pkg: example.com/b",
+		"BenchmarkEncode  	 50	  31735022 ns/op",
+	}
+	stats, err := benchcheck.Stat(results, results)
+	assert.NoError(t, err)
+
+	got := &strings.Builder{}
+	err = benchcheck.WriteMarkdown(got, benchcheck.NewReport(stats, nil))
+	assert.NoError(t, err)
+
+	for _, want := range []string{
+		"| Package | Benchmark | Old | New | Delta |\n",
+		"| example.com/a | Encode |",
+		"| example.com/b | Encode |",
+	} {
+		if !strings.Contains(got.String(), want) {
+			t.Fatalf("want %q on report, got:\n%s", want, got)
+		}
+	}
+}
+
+func statTestdataFiles(t *testing.T, oldname, newname string) []benchcheck.StatResult {
+	t.Helper()
+	return statTestdataFilesWithOptions(t, oldname, newname, benchcheck.StatOptions{})
+}
+
+func assertGolden(t *testing.T, path string, got string) {
+	t.Helper()
+
+	if *update {
+		assert.NoError(t, os.WriteFile(path, []byte(got), 0644))
+	}
+
+	want, err := os.ReadFile(path)
+	assert.NoError(t, err, "reading golden file (use -update to create it)")
+
+	if diff := cmp.Diff(string(want), got); diff != "" {
+		t.Fatalf("%s differs (use -update to update it): %s", path, diff)
+	}
+}
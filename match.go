@@ -0,0 +1,117 @@
+package benchcheck
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// BenchRegexp is a benchmark regular expression matched against
+// benchmark names like "go test -bench" does. The expression is split
+// by "/" into one regular expression per sub-benchmark level, each
+// one matched against the same level of the benchmark name, and top
+// level "|" separates alternatives, like "BenchmarkEncode/small|Decode".
+type BenchRegexp struct {
+	expr         string
+	alternatives [][]*regexp.Regexp
+}
+
+// procsSuffix is the "-N" suffix go test adds to benchmark
+// names when they run with GOMAXPROCS other than 1.
+var procsSuffix = regexp.MustCompile(`-[0-9]+$`)
+
+// CompileBenchRegexp parses a benchmark regular expression.
+func CompileBenchRegexp(expr string) (*BenchRegexp, error) {
+	if _, err := regexp.Compile(expr); err != nil {
+		return nil, err
+	}
+
+	re := &BenchRegexp{expr: expr}
+	for _, alternative := range splitBenchRegexp(expr) {
+		levels := make([]*regexp.Regexp, len(alternative))
+		for i, level := range alternative {
+			compiled, err := regexp.Compile(level)
+			if err != nil {
+				return nil, fmt.Errorf("element %d of %q (%q): %v", i, expr, level, err)
+			}
+			levels[i] = compiled
+		}
+		re.alternatives = append(re.alternatives, levels)
+	}
+	return re, nil
+}
+
+// MatchString returns true if the expression matches the benchmark with
+// the given name, as reported on results, like "Encode/small-8". The
+// "Benchmark" prefix is added to the name and its GOMAXPROCS suffix is
+// removed before matching, so "BenchmarkEncode$" matches "Encode-8".
+// Like "go test -bench", names with less levels than the expression
+// match if all their levels do.
+func (r *BenchRegexp) MatchString(name string) bool {
+	levels := strings.Split("Benchmark"+procsSuffix.ReplaceAllString(name, ""), "/")
+	for _, alternative := range r.alternatives {
+		if matchLevels(alternative, levels) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the source text of the expression.
+func (r *BenchRegexp) String() string {
+	return r.expr
+}
+
+func matchLevels(res []*regexp.Regexp, levels []string) bool {
+	for i, level := range levels {
+		if i >= len(res) {
+			break
+		}
+		if !res[i].MatchString(level) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitBenchRegexp splits the expression like "go test -bench" does,
+// first on top level "|" and then on "/", ignoring both inside
+// brackets and parentheses or when escaped.
+func splitBenchRegexp(expr string) [][]string {
+	var alternatives [][]string
+	var levels []string
+	brackets, parens := 0, 0
+	start := 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '[':
+			brackets++
+		case ']':
+			if brackets--; brackets < 0 {
+				brackets = 0
+			}
+		case '(':
+			if brackets == 0 {
+				parens++
+			}
+		case ')':
+			if brackets == 0 {
+				parens--
+			}
+		case '\\':
+			i++
+		case '/':
+			if brackets == 0 && parens == 0 {
+				levels = append(levels, expr[start:i])
+				start = i + 1
+			}
+		case '|':
+			if brackets == 0 && parens == 0 {
+				alternatives = append(alternatives, append(levels, expr[start:i]))
+				levels = nil
+				start = i + 1
+			}
+		}
+	}
+	return append(alternatives, append(levels, expr[start:]))
+}
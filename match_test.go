@@ -0,0 +1,52 @@
+package benchcheck_test
+
+import (
+	"testing"
+
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestBenchRegexp(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		expr string
+		name string
+		want bool
+	}
+
+	tcases := []testcase{
+		{expr: "Encode", name: "Encode-8", want: true},
+		{expr: "BenchmarkEncode$", name: "Encode-8", want: true},
+		{expr: "BenchmarkEncode$", name: "Encode", want: true},
+		{expr: "BenchmarkEncode$", name: "EncodeFast-8", want: false},
+		{expr: "^Encode", name: "Encode-8", want: false},
+		{expr: "Encode-8", name: "Encode-8", want: false},
+		{expr: "Encode$", name: "Encode/small-8", want: true},
+		{expr: "Encode/small", name: "Encode/small-8", want: true},
+		{expr: "Encode/small", name: "Encode/large-8", want: false},
+		{expr: "Encode/^small$", name: "Encode/small-4", want: true},
+		{expr: "Encode/^small$", name: "Encode/smaller-4", want: false},
+		{expr: "Encode/small", name: "Encode-8", want: true},
+		{expr: "Encode/(small|large)", name: "Encode/large-8", want: true},
+		{expr: "Encode/[/]", name: "Encode/small-8", want: false},
+		{expr: "Decode|Encode/small", name: "Decode/large-8", want: true},
+		{expr: "Decode|Encode/small", name: "Encode/large-8", want: false},
+		{expr: "Decode|Encode/small", name: "Encode/small-8", want: true},
+	}
+
+	for _, tcase := range tcases {
+		re, err := benchcheck.CompileBenchRegexp(tcase.expr)
+		assert.NoError(t, err)
+		assert.EqualStrings(t, tcase.expr, re.String())
+
+		if got := re.MatchString(tcase.name); got != tcase.want {
+			t.Errorf("CompileBenchRegexp(%q).MatchString(%q)=%t; want %t",
+				tcase.expr, tcase.name, got, tcase.want)
+		}
+	}
+
+	_, err := benchcheck.CompileBenchRegexp("Encode[")
+	assert.Error(t, err)
+}
@@ -0,0 +1,193 @@
+package benchcheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCount is the default number of times each benchmark runs.
+// benchstat requires multiple runs of the same benchmarks
+// so it can assess statistically for abnormalities, etc.
+const DefaultCount = 5
+
+// RunOptions configures how benchmarks are run.
+// The zero value runs all benchmarks of all packages of a module
+// without running any tests.
+type RunOptions struct {
+	// Bench is the regular expression selecting which benchmarks
+	// will run, as in "go test -bench". If empty, all benchmarks run.
+	Bench string
+	// Run is the regular expression selecting which tests will
+	// run, as in "go test -run". If empty, no tests run.
+	Run string
+	// Packages are the package patterns to be benchmarked, relative
+	// to the module root, like "./pkg/...". If empty, all
+	// packages of the module are benchmarked.
+	Packages []string
+	// Tags are build tags to be considered while building.
+	Tags []string
+	// Count is how many times each benchmark runs, as in "go test -count",
+	// which is the number of samples available for the stats.
+	// If zero, RunBench runs each benchmark once while StatModule
+	// runs each benchmark DefaultCount times.
+	Count int
+	// BenchTime is how long each benchmark runs, as in "go test -benchtime".
+	// It can be a duration like "1s" or a number of iterations like "100x".
+	// If empty, the go test default is used.
+	BenchTime string
+	// CPU is the list of GOMAXPROCS values each benchmark runs with,
+	// as in "go test -cpu". If empty, the current GOMAXPROCS is used.
+	CPU []int
+	// Timeout is the timeout of each go test execution, as in
+	// "go test -timeout". If zero, the go test default is used.
+	Timeout time.Duration
+	// NoBenchMem disables memory allocation statistics. By default
+	// benchmarks run with "go test -benchmem".
+	NoBenchMem bool
+	// Tolerant keeps the results of the packages whose benchmarks
+	// succeeded when the benchmarks of other packages fail, returning
+	// them along with a *PartialError recording the failed packages.
+	// By default any failure discards all results.
+	Tolerant bool
+}
+
+// Option configures the functions running or comparing benchmarks.
+// Each function honours only the options that apply to it, ignoring
+// the others:
+//
+// - RunBench: WithRunOptions and WithRunner.
+// - Stat and StatFiles: WithStatOptions.
+// - StatModule and StatModules: all options.
+type Option func(*config)
+
+type config struct {
+	run      RunOptions
+	stat     StatOptions
+	schedule Schedule
+	onRun    func(BenchRun)
+	onEvent  func(Event)
+	runner   Runner
+}
+
+// WithRunOptions sets the options used to run the benchmarks.
+func WithRunOptions(opts RunOptions) Option {
+	return func(c *config) {
+		c.run = opts
+	}
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Validate checks if the options are valid, returning an error if not.
+func (o RunOptions) Validate() error {
+	if o.Count < 0 {
+		return fmt.Errorf("run options: count %d must not be negative", o.Count)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("run options: timeout %v must not be negative", o.Timeout)
+	}
+	for _, cpu := range o.CPU {
+		if cpu <= 0 {
+			return fmt.Errorf("run options: cpu %d must be positive", cpu)
+		}
+	}
+	if o.BenchTime == "" {
+		return nil
+	}
+	if iterations := strings.TrimSuffix(o.BenchTime, "x"); iterations != o.BenchTime {
+		if n, err := strconv.Atoi(iterations); err != nil || n <= 0 {
+			return fmt.Errorf("run options: invalid benchtime %q: iterations must be a positive integer", o.BenchTime)
+		}
+		return nil
+	}
+	if d, err := time.ParseDuration(o.BenchTime); err != nil || d <= 0 {
+		return fmt.Errorf("run options: invalid benchtime %q: must be a positive duration or Nx", o.BenchTime)
+	}
+	return nil
+}
+
+// withDefaultCount returns a copy of the options using
+// DefaultCount if no count was provided.
+func (o RunOptions) withDefaultCount() RunOptions {
+	if o.Count == 0 {
+		o.Count = DefaultCount
+	}
+	return o
+}
+
+// goTestArgs returns the arguments used to run "go test".
+func (o RunOptions) goTestArgs() []string {
+	args := append([]string{"test"}, o.buildFlags()...)
+	args = append(args, o.testFlags("-")...)
+	return append(args, o.packages()...)
+}
+
+// testBinaryArgs returns the arguments used to run a
+// test binary compiled with "go test -c".
+func (o RunOptions) testBinaryArgs() []string {
+	if o.Timeout == 0 {
+		// Same default timeout go test uses.
+		o.Timeout = 10 * time.Minute
+	}
+	return o.testFlags("-test.")
+}
+
+// buildFlags returns the flags used to build the tests.
+func (o RunOptions) buildFlags() []string {
+	if len(o.Tags) == 0 {
+		return nil
+	}
+	return []string{"-tags=" + strings.Join(o.Tags, ",")}
+}
+
+// testFlags returns the flags used to run the tests with the given
+// prefix, since test binaries expect flags like "-test.bench"
+// instead of "-bench".
+func (o RunOptions) testFlags(prefix string) []string {
+	bench := o.Bench
+	if bench == "" {
+		bench = "."
+	}
+	run := o.Run
+	if run == "" {
+		run = "^$"
+	}
+
+	flags := []string{prefix + "run=" + run, prefix + "bench=" + bench}
+	if !o.NoBenchMem {
+		flags = append(flags, prefix+"benchmem")
+	}
+	if o.Count > 0 {
+		flags = append(flags, prefix+"count="+strconv.Itoa(o.Count))
+	}
+	if o.BenchTime != "" {
+		flags = append(flags, prefix+"benchtime="+o.BenchTime)
+	}
+	if len(o.CPU) > 0 {
+		cpus := make([]string, len(o.CPU))
+		for i, cpu := range o.CPU {
+			cpus[i] = strconv.Itoa(cpu)
+		}
+		flags = append(flags, prefix+"cpu="+strings.Join(cpus, ","))
+	}
+	if o.Timeout > 0 {
+		flags = append(flags, prefix+"timeout="+o.Timeout.String())
+	}
+	return flags
+}
+
+// packages returns the package patterns to be benchmarked.
+func (o RunOptions) packages() []string {
+	if len(o.Packages) == 0 {
+		return []string{"./..."}
+	}
+	return o.Packages
+}
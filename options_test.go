@@ -0,0 +1,87 @@
+package benchcheck_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestRunOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name    string
+		opts    benchcheck.RunOptions
+		wantErr bool
+	}
+
+	tcases := []testcase{
+		{
+			name: "zero value",
+		},
+		{
+			name: "all valid",
+			opts: benchcheck.RunOptions{
+				Count:     10,
+				BenchTime: "2s",
+				CPU:       []int{1, 4},
+				Timeout:   time.Hour,
+			},
+		},
+		{
+			name: "benchtime iterations",
+			opts: benchcheck.RunOptions{BenchTime: "100x"},
+		},
+		{
+			name:    "negative count",
+			opts:    benchcheck.RunOptions{Count: -1},
+			wantErr: true,
+		},
+		{
+			name:    "negative timeout",
+			opts:    benchcheck.RunOptions{Timeout: -time.Second},
+			wantErr: true,
+		},
+		{
+			name:    "zero cpu",
+			opts:    benchcheck.RunOptions{CPU: []int{1, 0}},
+			wantErr: true,
+		},
+		{
+			name:    "benchtime no unit",
+			opts:    benchcheck.RunOptions{BenchTime: "10"},
+			wantErr: true,
+		},
+		{
+			name:    "benchtime zero iterations",
+			opts:    benchcheck.RunOptions{BenchTime: "0x"},
+			wantErr: true,
+		},
+		{
+			name:    "benchtime invalid iterations",
+			opts:    benchcheck.RunOptions{BenchTime: "onex"},
+			wantErr: true,
+		},
+		{
+			name:    "benchtime negative duration",
+			opts:    benchcheck.RunOptions{BenchTime: "-1s"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tcase.opts.Validate()
+			if tcase.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package benchcheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PackageFailure is a package whose benchmarks failed, like when a
+// benchmark panics or calls b.Fatal, or whose tests failed to build.
+type PackageFailure struct {
+	// Side is the module of the failed package, it is only set
+	// by StatModule and StatModules.
+	Side Side `json:"side,omitempty"`
+	// Package is the import path of the failed package.
+	Package string `json:"package"`
+	// Output is the output of the failed package.
+	Output string `json:"output"`
+}
+
+// PartialError is returned, along with the results of the packages
+// whose benchmarks succeeded, when running with RunOptions.Tolerant
+// and the benchmarks of some packages failed.
+//
+// Callers should check for it with errors.As before discarding
+// the results, since all other errors return no results.
+type PartialError struct {
+	// Failures has all the failed packages.
+	Failures []PackageFailure
+}
+
+// String returns the package and the side it failed on, if known.
+func (f PackageFailure) String() string {
+	if f.Side == "" {
+		return f.Package
+	}
+	return fmt.Sprintf("%s (%s)", f.Package, f.Side)
+}
+
+// Error returns the string representation of the error.
+func (p *PartialError) Error() string {
+	pkgs := make([]string, len(p.Failures))
+	for i, failure := range p.Failures {
+		pkgs[i] = failure.String()
+	}
+	return fmt.Sprintf("benchmarks failed on packages: %s", strings.Join(pkgs, ", "))
+}
+
+// goTestFailures parses the output of a failed "go test" returning
+// the failed packages, each with the output printed for the package.
+func goTestFailures(output string) []PackageFailure {
+	var failures []PackageFailure
+	pkgOutput := &strings.Builder{}
+
+	for _, line := range strings.Split(output, "\n") {
+		pkgOutput.WriteString(line)
+		pkgOutput.WriteString("\n")
+
+		// go test ends the output of each package with a summary
+		// like "ok  \tpkg\t0.1s", "FAIL\tpkg\t0.1s" or
+		// "FAIL\tpkg [build failed]".
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "FAIL":
+			failures = append(failures, PackageFailure{
+				Package: fields[1],
+				Output:  pkgOutput.String(),
+			})
+		case "ok", "?":
+		default:
+			continue
+		}
+		pkgOutput.Reset()
+	}
+
+	return failures
+}
+
+// addFailures adds the failures of the given side, ignoring
+// packages that already failed on the same side.
+func addFailures(failures []PackageFailure, side Side, newFailures []PackageFailure) []PackageFailure {
+	for _, failure := range newFailures {
+		failure.Side = side
+		found := false
+		for _, f := range failures {
+			if f.Side == failure.Side && f.Package == failure.Package {
+				found = true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, failure)
+		}
+	}
+	return failures
+}
@@ -0,0 +1,172 @@
+package benchcheck_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestRunBenchTolerant(t *testing.T) {
+	t.Parallel()
+
+	mod := brokenModule(t)
+	opts := benchcheck.RunOptions{BenchTime: "1x"}
+
+	res, err := benchcheck.RunBench(mod, benchcheck.WithRunOptions(opts))
+	var cmderr *benchcheck.CmdError
+	if !errors.As(err, &cmderr) {
+		t.Fatalf("want *benchcheck.CmdError without tolerant mode, got: %v", err)
+	}
+	assert.EqualInts(t, 0, len(res), "want no results, got: %v", res)
+
+	opts.Tolerant = true
+
+	res, err = benchcheck.RunBench(mod, benchcheck.WithRunOptions(opts))
+	failures := assertPartial(t, err)
+	assert.EqualInts(t, 1, len(failures), "want single failed package, got: %v", failures)
+	assert.EqualStrings(t, "bench/broken", failures[0].Package)
+	if !strings.Contains(failures[0].Output, "boom") {
+		t.Fatalf("want failed package output with the panic, got: %q", failures[0].Output)
+	}
+	assertBenchmarks(t, []string{"BenchmarkOK"}, res)
+}
+
+func TestStatModulesTolerant(t *testing.T) {
+	t.Parallel()
+
+	mod := brokenModule(t)
+
+	stats, err := benchcheck.StatModules(mod, mod, benchcheck.WithRunOptions(benchcheck.RunOptions{
+		Count:     2,
+		BenchTime: "1x",
+		Tolerant:  true,
+	}))
+	failures := assertPartial(t, err)
+
+	sides := []benchcheck.Side{}
+	for _, failure := range failures {
+		assert.EqualStrings(t, "bench/broken", failure.Package)
+		sides = append(sides, failure.Side)
+	}
+	if diff := cmp.Diff([]benchcheck.Side{benchcheck.OldSide, benchcheck.NewSide}, sides); diff != "" {
+		t.Fatalf("want one failure per module: %s", diff)
+	}
+
+	if len(stats) == 0 || len(stats[0].BenchDiffs) != 1 || stats[0].BenchDiffs[0].Name != "OK" {
+		t.Fatalf("want stats of the succeeded package, got: %v", stats)
+	}
+}
+
+func TestStatModulesTolerantBuildFailures(t *testing.T) {
+	t.Parallel()
+
+	mod := benchModule(t, map[string]string{
+		"ok/ok_test.go": `package ok
+
+import "testing"
+
+func BenchmarkOK(b *testing.B) {}
+`,
+		"nocompile/nocompile_test.go": `package nocompile
+
+import "testing"
+
+func BenchmarkNoCompile(b *testing.B) {
+	unused := 1
+}
+`,
+		"noimport/noimport.go": `package noimport
+
+import _ "bench/missing"
+`,
+		"noimport/noimport_test.go": `package noimport
+
+import "testing"
+
+func BenchmarkNoImport(b *testing.B) {}
+`,
+	})
+	opts := benchcheck.RunOptions{Count: 2, BenchTime: "1x"}
+
+	_, err := benchcheck.StatModules(mod, mod, benchcheck.WithRunOptions(opts))
+	var cmderr *benchcheck.CmdError
+	if !errors.As(err, &cmderr) {
+		t.Fatalf("want *benchcheck.CmdError without tolerant mode, got: %v", err)
+	}
+
+	opts.Tolerant = true
+	stats, err := benchcheck.StatModules(mod, mod, benchcheck.WithRunOptions(opts))
+	failures := assertPartial(t, err)
+
+	got := []string{}
+	for _, failure := range failures {
+		got = append(got, failure.String())
+	}
+	want := []string{
+		"bench/nocompile (old)",
+		"bench/noimport (old)",
+		"bench/nocompile (new)",
+		"bench/noimport (new)",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("want build failures of each module: %s", diff)
+	}
+
+	if len(stats) == 0 || len(stats[0].BenchDiffs) != 1 || stats[0].BenchDiffs[0].Name != "OK" {
+		t.Fatalf("want stats of the built package, got: %v", stats)
+	}
+}
+
+// brokenModule returns a module with a package whose benchmarks
+// succeed and a package whose benchmark panics.
+func brokenModule(t *testing.T) benchcheck.Module {
+	t.Helper()
+
+	return benchModule(t, map[string]string{
+		"ok/ok_test.go": `package ok
+
+import "testing"
+
+func BenchmarkOK(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+	}
+}
+`,
+		"broken/broken_test.go": `package broken
+
+import "testing"
+
+func BenchmarkBroken(b *testing.B) {
+	panic("boom")
+}
+`,
+	})
+}
+
+func assertPartial(t *testing.T, err error) []benchcheck.PackageFailure {
+	t.Helper()
+
+	var partial *benchcheck.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("want *benchcheck.PartialError, got: %v", err)
+	}
+	return partial.Failures
+}
+
+func assertBenchmarks(t *testing.T, wantPrefixes []string, res benchcheck.BenchResults) {
+	t.Helper()
+
+	results := benchmarkResults(res)
+	if len(wantPrefixes) != len(results) {
+		t.Fatalf("want results %v, got: %v", wantPrefixes, res)
+	}
+	for i, prefix := range wantPrefixes {
+		if !strings.HasPrefix(results[i], prefix) {
+			t.Fatalf("want result %d with prefix %q, got: %q", i, prefix, results[i])
+		}
+	}
+}
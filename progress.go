@@ -0,0 +1,68 @@
+package benchcheck
+
+// EventKind identifies what happened on an Event.
+type EventKind string
+
+const (
+	// ModuleResolved happens when a module version was downloaded
+	// by StatModule. Event.Module is the resolved module.
+	ModuleResolved EventKind = "module_resolved"
+	// BuildStarted happens before the test binaries of a module are built,
+	// which doesn't happen when a Runner is set with WithRunner.
+	BuildStarted EventKind = "build_started"
+	// BuildFinished happens after the test binaries of a module are built.
+	// Event.Packages has the packages with test binaries.
+	BuildFinished EventKind = "build_finished"
+	// RunStarted happens before each benchmark run of the schedule.
+	RunStarted EventKind = "run_started"
+	// BenchParsed happens for each benchmark result as soon as the
+	// benchmark finishes, while the run is still going on.
+	// Event.Result is the benchmark result.
+	BenchParsed EventKind = "bench_parsed"
+	// RunFinished happens after each benchmark run of the schedule.
+	// Event.Results has all benchmark results of the run.
+	RunFinished EventKind = "run_finished"
+	// StatsComputed happens after the old and new results are compared.
+	// Event.Stats has the compared results.
+	StatsComputed EventKind = "stats_computed"
+)
+
+// Event reports the progress of StatModule and StatModules, which can
+// take many minutes to finish. Only the fields relevant to the Kind of
+// the event are set.
+type Event struct {
+	// Kind is what happened.
+	Kind EventKind
+	// Side is the module the event refers to, if any.
+	Side Side
+	// Module is the module the event refers to, if any.
+	Module Module
+	// Packages are the import paths of the built packages.
+	Packages []string
+	// Seq is the position of the run on the schedule, starting at 0.
+	Seq int
+	// Runs is the total number of runs on the schedule.
+	Runs int
+	// Result is a single benchmark result of a run.
+	Result string
+	// Results are all benchmark results of a run.
+	Results BenchResults
+	// Stats are the compared results.
+	Stats []StatResult
+}
+
+// OnEvent registers a function that will be called with the progress
+// events of StatModule and StatModules as they happen. The function is
+// called sequentially, on the goroutine calling StatModule or StatModules,
+// so slow functions delay the benchmarks.
+func OnEvent(fn func(Event)) Option {
+	return func(c *config) {
+		c.onEvent = fn
+	}
+}
+
+func (c config) emit(event Event) {
+	if c.onEvent != nil {
+		c.onEvent(event)
+	}
+}
@@ -0,0 +1,89 @@
+package benchcheck_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/benchcheck"
+)
+
+func TestStatModulesEvents(t *testing.T) {
+	t.Parallel()
+
+	mod := benchModule(t, map[string]string{
+		"bench_test.go": `package bench
+
+import "testing"
+
+func BenchmarkA(b *testing.B) {}
+func BenchmarkB(b *testing.B) {}
+`,
+	})
+
+	events := []benchcheck.Event{}
+	stats, err := benchcheck.StatModules(mod, mod,
+		benchcheck.WithRunOptions(benchcheck.RunOptions{Count: 2, BenchTime: "1x"}),
+		benchcheck.OnEvent(func(event benchcheck.Event) {
+			events = append(events, event)
+		}),
+	)
+	assertNoError(t, err)
+
+	kinds := []benchcheck.EventKind{}
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+	}
+	wantRun := []benchcheck.EventKind{
+		benchcheck.RunStarted,
+		benchcheck.BenchParsed,
+		benchcheck.BenchParsed,
+		benchcheck.RunFinished,
+	}
+	want := []benchcheck.EventKind{
+		benchcheck.BuildStarted,
+		benchcheck.BuildFinished,
+		benchcheck.BuildStarted,
+		benchcheck.BuildFinished,
+	}
+	for i := 0; i < 4; i++ {
+		want = append(want, wantRun...)
+	}
+	want = append(want, benchcheck.StatsComputed)
+
+	if diff := cmp.Diff(want, kinds); diff != "" {
+		t.Fatalf("event kinds: %s", diff)
+	}
+
+	builds := events[:4]
+	for i, side := range []benchcheck.Side{benchcheck.OldSide, benchcheck.NewSide} {
+		finished := builds[i*2+1]
+		if finished.Side != side {
+			t.Fatalf("got build of %q module, want %q", finished.Side, side)
+		}
+		if diff := cmp.Diff([]string{"bench"}, finished.Packages); diff != "" {
+			t.Fatalf("built packages: %s", diff)
+		}
+	}
+
+	for i, event := range events[4 : len(events)-1] {
+		seq := i / len(wantRun)
+		if event.Seq != seq || event.Runs != 4 {
+			t.Fatalf("got run %d/%d on event %v, want %d/4", event.Seq, event.Runs, event.Kind, seq)
+		}
+		switch event.Kind {
+		case benchcheck.BenchParsed:
+			if !strings.HasPrefix(event.Result, "Benchmark") {
+				t.Fatalf("want benchmark result on %v event, got %q", event.Kind, event.Result)
+			}
+		case benchcheck.RunFinished:
+			if len(benchmarkResults(event.Results)) != 2 {
+				t.Fatalf("want 2 results on %v event, got: %v", event.Kind, event.Results)
+			}
+		}
+	}
+
+	if diff := cmp.Diff(stats, events[len(events)-1].Stats); diff != "" {
+		t.Fatalf("computed stats event: %s", diff)
+	}
+}
@@ -0,0 +1,216 @@
+package benchcheck
+
+import "encoding/json"
+
+// ReportVersion is the version of the Report JSON schema.
+// It changes only when the schema changes in a backward
+// incompatible way, new fields may be added on the same version.
+const ReportVersion = 1
+
+// removedCheck is how the check of removed benchmarks,
+// enabled by Report.FailRemoved, is named on reports.
+const removedCheck = "removed benchmarks"
+
+// Report is the full outcome of comparing benchmarks, with all stat
+// results and the verdicts of all checkers performed on them.
+type Report struct {
+	// Version is the schema version of the report, see ReportVersion.
+	Version int `json:"version"`
+	// Results are all the compared metrics.
+	Results []StatResult `json:"results"`
+	// Checks has the report of each checker performed on the results.
+	Checks []CheckReport `json:"checks"`
+	// Failures has the packages whose benchmarks failed when running
+	// with RunOptions.Tolerant, their benchmarks may be missing from
+	// the results or reported as removed or added.
+	Failures []PackageFailure `json:"failures,omitempty"`
+	// FailRemoved is true if benchmarks that were removed, existing
+	// only on the old results, fail the report. Deleting or renaming
+	// a benchmark can hide a regression, so this is the check for it.
+	FailRemoved bool `json:"fail_removed"`
+}
+
+// NewReport creates a report by evaluating all the given checkers
+// on the results. Each checker has a single report in the same order
+// as the given checkers.
+//
+// Each benchmark is checked only by the most specific checkers of its
+// metric that match it. A checker is more specific than another if the
+// benchmarks of the results it matches are a subset of the ones matched
+// by the other, so a checker like "BenchmarkEncode:time/op=+2%" takes
+// precedence over "Benchmark.+:time/op=+20%" or "time/op=+20%" for the
+// benchmarks it matches. Checkers matching the same benchmarks with
+// different regular expressions are as specific as they are declared,
+// the last one being the most specific, while checkers with the same
+// regular expression, like "time/op=+10%" and "time/op<1ms", all
+// check the benchmark.
+func NewReport(results []StatResult, checkers []Checker) Report {
+	report := Report{
+		Version: ReportVersion,
+		Results: results,
+		Checks:  make([]CheckReport, len(checkers)),
+	}
+	if report.Results == nil {
+		report.Results = []StatResult{}
+	}
+
+	selected := newSelection(checkers, results)
+	for i, checker := range checkers {
+		check := CheckReport{
+			Checker: checker,
+			Metric:  checker.Metric(),
+		}
+		for _, result := range results {
+			eval := checker.Evaluate(selected.checkedBy(i, result))
+			check.Violations = append(check.Violations, eval.Violations...)
+			check.Inconclusive = append(check.Inconclusive, eval.Inconclusive...)
+		}
+		report.Checks[i] = check
+	}
+
+	return report
+}
+
+// selection selects the most specific checkers of each benchmark,
+// see NewReport, based on the benchmarks matched by each checker.
+type selection struct {
+	checkers []Checker
+	matches  []map[string]bool
+}
+
+func newSelection(checkers []Checker, results []StatResult) selection {
+	s := selection{
+		checkers: checkers,
+		matches:  make([]map[string]bool, len(checkers)),
+	}
+	for i, checker := range checkers {
+		s.matches[i] = map[string]bool{}
+		for _, result := range results {
+			for _, bench := range result.BenchDiffs {
+				if checker.Matches(bench.Name) {
+					s.matches[i][bench.Name] = true
+				}
+			}
+		}
+	}
+	return s
+}
+
+// checkedBy returns the result with only the benchmarks that
+// are checked by the checker at the given index.
+func (s selection) checkedBy(index int, result StatResult) StatResult {
+	checked := StatResult{Metric: result.Metric}
+	for _, bench := range result.BenchDiffs {
+		if s.checks(index, result.Metric, bench.Name) {
+			checked.BenchDiffs = append(checked.BenchDiffs, bench)
+		}
+	}
+	return checked
+}
+
+// checks returns true if the checker at the given index checks the
+// given benchmark, which happens when no other checker of the metric
+// that matches the benchmark is more specific.
+func (s selection) checks(index int, metric, bench string) bool {
+	if !s.matched(index, metric, bench) {
+		return false
+	}
+	for other := range s.checkers {
+		if other != index && s.matched(other, metric, bench) && s.moreSpecific(other, index) {
+			return false
+		}
+	}
+	return true
+}
+
+// checked returns true if any of the checkers checks the given benchmark.
+func (s selection) checked(metric, bench string) bool {
+	for i := range s.checkers {
+		if s.matched(i, metric, bench) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s selection) matched(index int, metric, bench string) bool {
+	checker := s.checkers[index]
+	return checker.Metric() == metric && checker.Matches(bench)
+}
+
+// moreSpecific returns true if the checker at index i is more
+// specific than the checker at index j.
+func (s selection) moreSpecific(i, j int) bool {
+	if s.checkers[i].benchRegexp() == s.checkers[j].benchRegexp() {
+		return false
+	}
+	for bench := range s.matches[i] {
+		if !s.matches[j][bench] {
+			return false
+		}
+	}
+	return len(s.matches[i]) < len(s.matches[j]) || i > j
+}
+
+// Passed returns true if all checks passed and, when FailRemoved
+// is true, no benchmarks were removed.
+func (r Report) Passed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed() {
+			return false
+		}
+	}
+	return !r.removedFailed()
+}
+
+// removedFailed returns true if the report fails because of
+// removed benchmarks.
+func (r Report) removedFailed() bool {
+	return r.FailRemoved && len(RemovedBenchmarks(r.Results)) > 0
+}
+
+// MarshalJSON encodes the report, including if it passed or not.
+func (r Report) MarshalJSON() ([]byte, error) {
+	// report has the same fields of Report without its methods,
+	// so encoding it doesn't recurse into MarshalJSON.
+	type report Report
+	return json.Marshal(struct {
+		report
+		Passed bool `json:"passed"`
+	}{
+		report: report(r),
+		Passed: r.Passed(),
+	})
+}
+
+// MarshalJSON encodes the checker as its string representation.
+func (c Checker) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// MarshalJSON encodes the report, including its status and if it passed or not.
+func (r CheckReport) MarshalJSON() ([]byte, error) {
+	violations := r.Violations
+	if violations == nil {
+		violations = []Violation{}
+	}
+	inconclusive := r.Inconclusive
+	if inconclusive == nil {
+		inconclusive = []Violation{}
+	}
+	return json.Marshal(struct {
+		Checker      Checker     `json:"checker"`
+		Metric       string      `json:"metric"`
+		Status       CheckStatus `json:"status"`
+		Passed       bool        `json:"passed"`
+		Violations   []Violation `json:"violations"`
+		Inconclusive []Violation `json:"inconclusive"`
+	}{
+		Checker:      r.Checker,
+		Metric:       r.Metric,
+		Status:       r.Status(),
+		Passed:       r.Passed(),
+		Violations:   violations,
+		Inconclusive: inconclusive,
+	})
+}
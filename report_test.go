@@ -0,0 +1,330 @@
+package benchcheck_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestNewReport(t *testing.T) {
+	t.Parallel()
+
+	slower := benchcheck.BenchDiff{
+		Name:        "Slower",
+		Old:         "1.00ms",
+		New:         "2.00ms",
+		Delta:       100.0,
+		Unit:        "ns/op",
+		OldStats:    benchcheck.BenchStats{Mean: 1e6, Samples: 5},
+		NewStats:    benchcheck.BenchStats{Mean: 2e6, Samples: 5},
+		PValue:      0.008,
+		Significant: true,
+	}
+	noisy := benchcheck.BenchDiff{
+		Name:     "Noisy",
+		Old:      "1.00ms ± 50%",
+		New:      "1.50ms ± 50%",
+		Unit:     "ns/op",
+		OldStats: benchcheck.BenchStats{Mean: 1e6, Variation: 50, Samples: 5},
+		NewStats: benchcheck.BenchStats{Mean: 1.5e6, Variation: 50, Samples: 5},
+		PValue:   0.2,
+	}
+	results := []benchcheck.StatResult{
+		{
+			Metric: benchcheck.TimeMetric,
+			BenchDiffs: []benchcheck.BenchDiff{
+				{Name: "Same", Old: "1.00ms", New: "1.00ms", Unit: "ns/op", PValue: -1},
+				slower,
+				noisy,
+			},
+		},
+		{
+			Metric: benchcheck.AllocsMetric,
+			BenchDiffs: []benchcheck.BenchDiff{
+				{Name: "Same", Old: "1.00", New: "1.00", Unit: "allocs/op", PValue: -1},
+			},
+		},
+	}
+
+	checkers := []benchcheck.Checker{}
+	for _, c := range []string{"time/op=+10%", "allocs/op=+10%", "speed=-10%"} {
+		checker, err := benchcheck.ParseChecker(c)
+		assert.NoError(t, err)
+		checkers = append(checkers, checker)
+	}
+	checkers = append(checkers, checkers[0].RequireSignificance(false))
+
+	report := benchcheck.NewReport(results, checkers)
+
+	assert.EqualInts(t, benchcheck.ReportVersion, report.Version)
+	assert.EqualInts(t, len(checkers), len(report.Checks))
+	if report.Passed() {
+		t.Fatal("want report to fail")
+	}
+
+	encoded, err := json.Marshal(report)
+	assert.NoError(t, err)
+
+	type violation struct {
+		Name      string  `json:"name"`
+		Delta     float64 `json:"delta"`
+		Threshold float64 `json:"threshold"`
+	}
+	type check struct {
+		Checker      string                 `json:"checker"`
+		Metric       string                 `json:"metric"`
+		Status       benchcheck.CheckStatus `json:"status"`
+		Passed       bool                   `json:"passed"`
+		Violations   []violation            `json:"violations"`
+		Inconclusive []violation            `json:"inconclusive"`
+	}
+	type encodedReport struct {
+		Version int                     `json:"version"`
+		Results []benchcheck.StatResult `json:"results"`
+		Checks  []check                 `json:"checks"`
+		Passed  bool                    `json:"passed"`
+	}
+
+	got := encodedReport{}
+	assert.NoError(t, json.Unmarshal(encoded, &got))
+
+	want := encodedReport{
+		Version: benchcheck.ReportVersion,
+		Results: results,
+		Checks: []check{
+			{
+				Checker: "time/op=+10%",
+				Metric:  "time/op",
+				Status:  benchcheck.CheckFailed,
+				Violations: []violation{
+					{Name: "Slower", Delta: 100.0, Threshold: 10.0},
+				},
+				Inconclusive: []violation{},
+			},
+			{
+				Checker:      "allocs/op=+10%",
+				Metric:       "allocs/op",
+				Status:       benchcheck.CheckPassed,
+				Passed:       true,
+				Violations:   []violation{},
+				Inconclusive: []violation{},
+			},
+			{
+				Checker:      "speed=-10%",
+				Metric:       "speed",
+				Status:       benchcheck.CheckPassed,
+				Passed:       true,
+				Violations:   []violation{},
+				Inconclusive: []violation{},
+			},
+			{
+				Checker: "time/op=+10%",
+				Metric:  "time/op",
+				Status:  benchcheck.CheckFailed,
+				Violations: []violation{
+					{Name: "Slower", Delta: 100.0, Threshold: 10.0},
+				},
+				Inconclusive: []violation{
+					{Name: "Noisy", Threshold: 10.0},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("encoded report %s differs: %s", encoded, diff)
+	}
+}
+
+func TestNewReportMostSpecificChecker(t *testing.T) {
+	t.Parallel()
+
+	results := []benchcheck.StatResult{
+		{
+			Metric: benchcheck.TimeMetric,
+			BenchDiffs: []benchcheck.BenchDiff{
+				{Name: "EncodeJSON-8", Delta: 5.0},
+				{Name: "EncodeGob-8", Delta: 5.0},
+				{Name: "Setup-8", Delta: 15.0},
+			},
+		},
+	}
+
+	checkers := []benchcheck.Checker{}
+	for _, c := range []string{
+		"time/op=+20%",
+		"time/op=-50%",
+		"BenchmarkEncode:time/op=+2%",
+		"BenchmarkEncodeGob:time/op=+10%",
+	} {
+		checker, err := benchcheck.ParseChecker(c)
+		assert.NoError(t, err)
+		checkers = append(checkers, checker)
+	}
+
+	report := benchcheck.NewReport(results, checkers)
+
+	got := map[string][]string{}
+	for _, check := range report.Checks {
+		for _, v := range check.Violations {
+			got[check.Checker.String()] = append(got[check.Checker.String()], v.Name)
+		}
+	}
+	// EncodeGob-8 violates "BenchmarkEncode:time/op=+2%" but it is
+	// checked only by the more specific "BenchmarkEncodeGob" checker.
+	// The generic checkers only check Setup-8.
+	want := map[string][]string{
+		"BenchmarkEncode:time/op=+2%": {"EncodeJSON-8"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("violations by checker: %s", diff)
+	}
+}
+
+func TestNewReportSubsetChecker(t *testing.T) {
+	t.Parallel()
+
+	results := []benchcheck.StatResult{
+		{
+			Metric: benchcheck.TimeMetric,
+			BenchDiffs: []benchcheck.BenchDiff{
+				{Name: "Encode-8", Delta: 10.0},
+				{Name: "EncodeFast-8", Delta: 10.0},
+				{Name: "Decode-8", Delta: 10.0},
+			},
+		},
+	}
+
+	type testcase struct {
+		name     string
+		checkers []string
+		want     map[string][]string
+	}
+
+	tcases := []testcase{
+		{
+			name:     "narrower shorter regex",
+			checkers: []string{"Encode:time/op=+2%", "Benchmark.+:time/op=+20%"},
+			want: map[string][]string{
+				"Encode:time/op=+2%": {"Encode-8", "EncodeFast-8"},
+			},
+		},
+		{
+			name:     "anchored regex",
+			checkers: []string{"time/op=+20%", "BenchmarkEncode$:time/op=+2%"},
+			want: map[string][]string{
+				"BenchmarkEncode$:time/op=+2%": {"Encode-8"},
+			},
+		},
+		{
+			name:     "last declared wins on same benchmarks",
+			checkers: []string{"Benchmark.+:time/op=+20%", "time/op=+2%"},
+			want: map[string][]string{
+				"time/op=+2%": {"Encode-8", "EncodeFast-8", "Decode-8"},
+			},
+		},
+		{
+			name:     "last declared wins on same benchmarks reversed",
+			checkers: []string{"time/op=+2%", "Benchmark.+:time/op=+20%"},
+			want:     map[string][]string{},
+		},
+		{
+			name:     "same regex all check",
+			checkers: []string{"Encode:time/op=+20%", "Encode:time/op=+2%", "Encode:time/op<1ms"},
+			want: map[string][]string{
+				"Encode:time/op=+2%": {"Encode-8", "EncodeFast-8"},
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			checkers := []benchcheck.Checker{}
+			for _, c := range tcase.checkers {
+				checker, err := benchcheck.ParseChecker(c)
+				assert.NoError(t, err)
+				checkers = append(checkers, checker)
+			}
+
+			got := map[string][]string{}
+			for _, check := range benchcheck.NewReport(results, checkers).Checks {
+				for _, v := range check.Violations {
+					got[check.Checker.String()] = append(got[check.Checker.String()], v.Name)
+				}
+			}
+			if diff := cmp.Diff(tcase.want, got); diff != "" {
+				t.Fatalf("violations by checker: %s", diff)
+			}
+		})
+	}
+}
+
+func TestReportFailRemoved(t *testing.T) {
+	t.Parallel()
+
+	results, err := benchcheck.Stat(
+		benchcheck.BenchResults{
+			"BenchmarkEncode  	 50	  31735022 ns/op",
+			"BenchmarkDecode  	 50	  31735022 ns/op",
+		},
+		benchcheck.BenchResults{
+			"BenchmarkEncode  	 50	  31735022 ns/op",
+		},
+	)
+	assert.NoError(t, err)
+
+	report := benchcheck.NewReport(results, nil)
+	if !report.Passed() {
+		t.Fatal("want report with removed benchmarks to pass by default")
+	}
+
+	report.FailRemoved = true
+	if report.Passed() {
+		t.Fatal("want report with removed benchmarks to fail with FailRemoved")
+	}
+
+	encoded, err := json.Marshal(report)
+	assert.NoError(t, err)
+	verdict := struct {
+		FailRemoved bool `json:"fail_removed"`
+		Passed      bool `json:"passed"`
+	}{}
+	assert.NoError(t, json.Unmarshal(encoded, &verdict))
+	if !verdict.FailRemoved || verdict.Passed {
+		t.Fatalf("want failed json report, got: %s", encoded)
+	}
+
+	md := &strings.Builder{}
+	assert.NoError(t, benchcheck.WriteMarkdown(md, report))
+	if want := "### Checks\n\n🔴 **removed benchmarks** failed:\n\n- Decode\n"; !strings.Contains(md.String(), want) {
+		t.Fatalf("want %q on markdown report, got:\n%s", want, md)
+	}
+
+	junit := &strings.Builder{}
+	assert.NoError(t, benchcheck.WriteJUnit(junit, report))
+	parsed := struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+	}{}
+	assert.NoError(t, xml.Unmarshal([]byte(junit.String()), &parsed))
+	// Decode removed + Encode skipped with no checkers.
+	assert.EqualInts(t, 2, parsed.Tests)
+	assert.EqualInts(t, 1, parsed.Failures)
+
+	report.Results = results[:0]
+	if !report.Passed() {
+		t.Fatal("want report with no removed benchmarks to pass with FailRemoved")
+	}
+	md.Reset()
+	assert.NoError(t, benchcheck.WriteMarkdown(md, report))
+	if want := "All 1 checks passed."; !strings.Contains(md.String(), want) {
+		t.Fatalf("want %q on markdown report, got:\n%s", want, md)
+	}
+}
@@ -0,0 +1,111 @@
+package benchcheck
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Runner runs the benchmarks of a module, selected by the given options,
+// returning the benchmark results. Runners allow benchmarks to run on
+// other ways than "go test", like executing prebuilt binaries, running
+// through wrappers like taskset or nice, or replaying recorded output.
+type Runner interface {
+	Run(ctx context.Context, mod Module, opts RunOptions) (BenchResults, error)
+}
+
+// RunnerFunc is an adapter to use ordinary functions as a Runner.
+type RunnerFunc func(ctx context.Context, mod Module, opts RunOptions) (BenchResults, error)
+
+// Run calls f(ctx, mod, opts).
+func (f RunnerFunc) Run(ctx context.Context, mod Module, opts RunOptions) (BenchResults, error) {
+	return f(ctx, mod, opts)
+}
+
+// GoTestRunner is the default Runner, running benchmarks with "go test"
+// on the module directory, exactly like RunBench.
+type GoTestRunner struct {
+	// Exec is the program, and its arguments, used to run the test
+	// binaries, as in "go test -exec". It can be used to run benchmarks
+	// through wrappers like []string{"taskset", "-c", "2"}. Arguments
+	// must not have spaces. If empty, test binaries are run directly.
+	Exec []string
+}
+
+// Run runs the benchmarks with "go test".
+//
+// Any errors running "go" can be inspected in detail by checking if
+// the returned error is a *CmdError, or a *CanceledError if the context
+// is done before "go test" finishes. With RunOptions.Tolerant, failed
+// packages are returned as a *PartialError along with the results.
+func (r GoTestRunner) Run(ctx context.Context, mod Module, opts RunOptions) (BenchResults, error) {
+	return r.runStream(ctx, mod, opts, func(string) {})
+}
+
+func (r GoTestRunner) runStream(ctx context.Context, mod Module, opts RunOptions, onResult func(string)) (BenchResults, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	args := opts.goTestArgs()
+	if len(r.Exec) > 0 {
+		args = append([]string{args[0], "-exec", strings.Join(r.Exec, " ")}, args[1:]...)
+	}
+	cmd := command(ctx, "go", args...)
+	cmd.Dir = mod.Path()
+
+	// The output is streamed so results are parsed as the benchmarks
+	// finish, instead of buffering everything until "go test" exits.
+	results := BenchResults{}
+	_, err := streamOutput(ctx, cmd, func(line string) {
+		if results.parse(line) {
+			onResult(line)
+		}
+	})
+	if err != nil {
+		var cmderr *CmdError
+		if opts.Tolerant && errors.As(err, &cmderr) {
+			// Failures not related to packages, like an invalid
+			// go.mod, still fail everything.
+			if failures := goTestFailures(cmderr.Output); len(failures) > 0 {
+				return results, &PartialError{Failures: failures}
+			}
+		}
+		return nil, err
+	}
+	return results, nil
+}
+
+// WithRunner sets the runner used to run the benchmarks of the old and
+// new modules on each run of the schedule, or of the module given to
+// RunBench. By default StatModule and StatModules build the test binaries
+// of each module once with "go test -c" and reuse them on all runs.
+func WithRunner(r Runner) Option {
+	return func(c *config) {
+		c.runner = r
+	}
+}
+
+// streamRunner is a Runner that can report each benchmark
+// result as soon as the benchmark finishes.
+type streamRunner interface {
+	runStream(ctx context.Context, mod Module, opts RunOptions, onResult func(string)) (BenchResults, error)
+}
+
+// runStream runs the benchmarks with the runner, calling onResult with
+// each benchmark result. Runners that can't stream results have them
+// reported after all benchmarks finish.
+func runStream(ctx context.Context, r Runner, mod Module, opts RunOptions, onResult func(string)) (BenchResults, error) {
+	if sr, ok := r.(streamRunner); ok {
+		return sr.runStream(ctx, mod, opts, onResult)
+	}
+	results, err := r.Run(ctx, mod, opts)
+	var partial *PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+	for _, result := range results {
+		onResult(result)
+	}
+	return results, err
+}
@@ -0,0 +1,134 @@
+package benchcheck_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestStatModulesWithRunner(t *testing.T) {
+	t.Parallel()
+
+	oldmod, err := benchcheck.LocalModule(t.TempDir())
+	assertNoError(t, err)
+	newmod, err := benchcheck.LocalModule(t.TempDir())
+	assertNoError(t, err)
+
+	// Replays recorded outputs, no Go module or go command involved.
+	recorded := map[string]string{
+		oldmod.Path(): filepath.Join("testdata", "old.txt"),
+		newmod.Path(): filepath.Join("testdata", "new.txt"),
+	}
+	runs := 0
+	replay := benchcheck.RunnerFunc(func(ctx context.Context, mod benchcheck.Module, opts benchcheck.RunOptions) (benchcheck.BenchResults, error) {
+		runs++
+		f, err := os.Open(recorded[mod.Path()])
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return benchcheck.ParseBenchResults(f)
+	})
+
+	events := map[benchcheck.EventKind]int{}
+	got, err := benchcheck.StatModules(oldmod, newmod,
+		benchcheck.WithRunner(replay),
+		benchcheck.WithSchedule(benchcheck.Sequential),
+		benchcheck.OnEvent(func(event benchcheck.Event) {
+			events[event.Kind]++
+		}),
+	)
+	assertNoError(t, err)
+
+	want := statTestdataFiles(t, "old.txt", "new.txt")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("replayed stats: %s", diff)
+	}
+
+	assert.EqualInts(t, 2, runs, "want one run per module")
+	assert.EqualInts(t, 0, events[benchcheck.BuildStarted], "want no builds with a runner")
+	assert.EqualInts(t, 2, events[benchcheck.RunFinished], "want one finished run event per module")
+	if events[benchcheck.BenchParsed] == 0 {
+		t.Fatal("want benchmark results reported by runners that don't stream")
+	}
+}
+
+func TestRunBenchWithRunner(t *testing.T) {
+	t.Parallel()
+
+	mod, err := benchcheck.LocalModule(t.TempDir())
+	assertNoError(t, err)
+
+	want := benchcheck.BenchResults{"BenchmarkFake-8 \t 100 \t 10 ns/op"}
+	runOpts := benchcheck.RunOptions{Bench: "Fake", Count: 2}
+	runner := benchcheck.RunnerFunc(func(ctx context.Context, got benchcheck.Module, opts benchcheck.RunOptions) (benchcheck.BenchResults, error) {
+		assert.EqualStrings(t, mod.Path(), got.Path())
+		if diff := cmp.Diff(runOpts, opts); diff != "" {
+			t.Errorf("run options: %s", diff)
+		}
+		return want, nil
+	})
+
+	// Events are only emitted by StatModule and StatModules.
+	events := 0
+	got, err := benchcheck.RunBench(mod,
+		benchcheck.WithRunner(runner),
+		benchcheck.WithRunOptions(runOpts),
+		benchcheck.OnEvent(func(benchcheck.Event) { events++ }),
+	)
+	assertNoError(t, err)
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("results: %s", diff)
+	}
+	assert.EqualInts(t, 0, events, "want no events from RunBench")
+}
+
+func TestGoTestRunnerExec(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("env is not available on windows")
+	}
+
+	mod := benchModule(t, map[string]string{
+		"bench_test.go": `package bench
+
+import (
+	"os"
+	"testing"
+)
+
+func BenchmarkWrapped(b *testing.B) {
+	if os.Getenv("BENCHCHECK_WRAPPED") != "1" {
+		b.Fatal("benchmark not running through the wrapper")
+	}
+}
+`,
+	})
+
+	runner := benchcheck.GoTestRunner{Exec: []string{"env", "BENCHCHECK_WRAPPED=1"}}
+	opts := benchcheck.RunOptions{BenchTime: "1x"}
+
+	res, err := runner.Run(context.Background(), mod, opts)
+	assertNoError(t, err)
+	assert.EqualInts(t, 1, len(benchmarkResults(res)), "want single result, got: %v", res)
+
+	_, err = benchcheck.GoTestRunner{}.Run(context.Background(), mod, opts)
+	assert.Error(t, err, "want benchmark failure without the wrapper")
+
+	stats, err := benchcheck.StatModules(mod, mod,
+		benchcheck.WithRunner(runner),
+		benchcheck.WithRunOptions(benchcheck.RunOptions{Count: 2, BenchTime: "1x"}),
+	)
+	assertNoError(t, err)
+	if len(stats) == 0 || len(stats[0].BenchDiffs) != 1 {
+		t.Fatalf("want stats of the wrapped benchmark, got: %v", stats)
+	}
+}
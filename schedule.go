@@ -0,0 +1,119 @@
+package benchcheck
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Schedule defines the order in which the benchmark runs of the
+// old and new modules happen.
+type Schedule int
+
+const (
+	// Interleaved alternates old and new runs: old, new, old, new...
+	// Each run produces a single sample of each benchmark, so any drift
+	// on the machine, like thermal throttling or background load,
+	// affects both modules similarly.
+	Interleaved Schedule = iota
+	// Randomized runs old and new in a random order, each one
+	// producing a single sample of each benchmark.
+	Randomized
+	// Sequential runs all old samples and then all new samples.
+	// It is the cheapest schedule since a single "go test" call with
+	// "-count" is done per module, but it is the most affected by drift.
+	Sequential
+)
+
+// Side identifies which module, old or new, a run belongs to.
+type Side string
+
+const (
+	// OldSide is the side of the old module.
+	OldSide Side = "old"
+	// NewSide is the side of the new module.
+	NewSide Side = "new"
+)
+
+// BenchRun is a single benchmark run of one of the modules being compared.
+type BenchRun struct {
+	// Seq is the position of the run on the schedule, starting at 0.
+	Seq int
+	// Side indicates the module of the run.
+	Side Side
+	// Results are the benchmark results of the run.
+	Results BenchResults
+}
+
+// WithSchedule sets the schedule of the benchmark runs.
+// The default is Interleaved.
+func WithSchedule(s Schedule) Option {
+	return func(c *config) {
+		c.schedule = s
+	}
+}
+
+// OnRun registers a function that will be called with the results
+// of each benchmark run, in the order the runs happened.
+func OnRun(fn func(BenchRun)) Option {
+	return func(c *config) {
+		c.onRun = fn
+	}
+}
+
+// ParseSchedule parses the given string as a Schedule.
+// Valid values are the ones returned by Schedule.String.
+func ParseSchedule(s string) (Schedule, error) {
+	for _, schedule := range []Schedule{Interleaved, Randomized, Sequential} {
+		if s == schedule.String() {
+			return schedule, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid schedule %q", s)
+}
+
+// String returns the string representation of the schedule.
+func (s Schedule) String() string {
+	switch s {
+	case Interleaved:
+		return "interleaved"
+	case Randomized:
+		return "randomized"
+	case Sequential:
+		return "sequential"
+	}
+	return fmt.Sprintf("Schedule(%d)", int(s))
+}
+
+// scheduledRun is a single planned run of one of the modules.
+type scheduledRun struct {
+	side  Side
+	count int
+}
+
+// plan returns the runs needed to get count samples of each module.
+func (s Schedule) plan(count int) ([]scheduledRun, error) {
+	switch s {
+	case Sequential:
+		return []scheduledRun{
+			{side: OldSide, count: count},
+			{side: NewSide, count: count},
+		}, nil
+	case Interleaved, Randomized:
+		runs := make([]scheduledRun, 0, count*2)
+		for i := 0; i < count; i++ {
+			runs = append(runs,
+				scheduledRun{side: OldSide, count: 1},
+				scheduledRun{side: NewSide, count: 1},
+			)
+		}
+		if s == Randomized {
+			rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+			rnd.Shuffle(len(runs), func(i, j int) {
+				runs[i], runs[j] = runs[j], runs[i]
+			})
+		}
+		return runs, nil
+	}
+	return nil, fmt.Errorf("invalid schedule %v", s)
+}
@@ -0,0 +1,118 @@
+package benchcheck_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestStatModulesSchedule(t *testing.T) {
+	t.Parallel()
+
+	const count = 3
+
+	mod := benchModule(t, map[string]string{
+		"bench_test.go": `package bench
+
+import "testing"
+
+func BenchmarkA(b *testing.B) {}
+func BenchmarkB(b *testing.B) {}
+`,
+	})
+
+	oldSide, newSide := benchcheck.OldSide, benchcheck.NewSide
+
+	type testcase struct {
+		name     string
+		schedule benchcheck.Schedule
+		// wantSides is the expected side of each run, if nil
+		// only the amount of runs of each side is checked.
+		wantSides []benchcheck.Side
+		// wantResults is the expected amount of results on each run.
+		wantResults int
+	}
+
+	tcases := []testcase{
+		{
+			name:        "interleaved",
+			schedule:    benchcheck.Interleaved,
+			wantSides:   []benchcheck.Side{oldSide, newSide, oldSide, newSide, oldSide, newSide},
+			wantResults: 2,
+		},
+		{
+			name:        "sequential",
+			schedule:    benchcheck.Sequential,
+			wantSides:   []benchcheck.Side{oldSide, newSide},
+			wantResults: 2 * count,
+		},
+		{
+			name:        "randomized",
+			schedule:    benchcheck.Randomized,
+			wantResults: 2,
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			runs := []benchcheck.BenchRun{}
+			_, err := benchcheck.StatModules(mod, mod,
+				benchcheck.WithRunOptions(benchcheck.RunOptions{
+					Count:     count,
+					BenchTime: "1x",
+				}),
+				benchcheck.WithSchedule(tcase.schedule),
+				benchcheck.OnRun(func(run benchcheck.BenchRun) {
+					runs = append(runs, run)
+				}),
+			)
+			assertNoError(t, err)
+
+			gotSides := make([]benchcheck.Side, len(runs))
+			sidesCount := map[benchcheck.Side]int{}
+			for i, run := range runs {
+				assert.EqualInts(t, i, run.Seq, "run %d has wrong seq", i)
+				assert.EqualInts(t, tcase.wantResults, len(benchmarkResults(run.Results)), "run %d: %v", i, run.Results)
+				gotSides[i] = run.Side
+				sidesCount[run.Side]++
+			}
+
+			if tcase.wantSides == nil {
+				assert.EqualInts(t, count, sidesCount[oldSide], "runs: %v", gotSides)
+				assert.EqualInts(t, count, sidesCount[newSide], "runs: %v", gotSides)
+				return
+			}
+			if diff := cmp.Diff(tcase.wantSides, gotSides); diff != "" {
+				t.Fatalf("got runs %v, want %v: %s", gotSides, tcase.wantSides, diff)
+			}
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	for _, want := range []benchcheck.Schedule{
+		benchcheck.Interleaved,
+		benchcheck.Randomized,
+		benchcheck.Sequential,
+	} {
+		got, err := benchcheck.ParseSchedule(want.String())
+		assert.NoError(t, err)
+		if got != want {
+			t.Fatalf("ParseSchedule(%q)=%v; want %v", want.String(), got, want)
+		}
+	}
+
+	_, err := benchcheck.ParseSchedule("StoNkS")
+	assert.Error(t, err)
+
+	_, err = benchcheck.StatModules(benchcheck.Module{}, benchcheck.Module{},
+		benchcheck.WithSchedule(benchcheck.Schedule(42)))
+	assert.Error(t, err, "want error on invalid schedule")
+}
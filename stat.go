@@ -0,0 +1,104 @@
+package benchcheck
+
+import (
+	"fmt"
+
+	"golang.org/x/perf/benchstat"
+)
+
+// DefaultAlpha is the default significance level used to decide if
+// a difference between old and new benchmarks is significant.
+// It is the same default used by benchstat.
+const DefaultAlpha = 0.05
+
+// StatTest is the statistical test used to compare the
+// samples of the old and new benchmarks.
+type StatTest int
+
+const (
+	// UTest is the Mann-Whitney U-test, which makes no assumptions
+	// about the distribution of the samples. It is the default.
+	UTest StatTest = iota
+	// TTest is Welch's two-sample t-test, which assumes the samples
+	// are normally distributed but may detect smaller differences.
+	TTest
+	// NoTest performs no statistical test, so all differences are
+	// considered significant, no matter how noisy the samples are.
+	NoTest
+)
+
+// StatOptions configures how benchmark results are compared.
+// The zero value uses the same defaults as benchstat.
+type StatOptions struct {
+	// Test is the statistical test used to compare the samples.
+	Test StatTest
+	// Alpha is the significance level: differences with a p-value
+	// greater than or equal to it are considered not significant
+	// and have a zero delta. If zero, DefaultAlpha is used.
+	Alpha float64
+	// GeoMean adds a "[Geo mean]" row to each metric, with the
+	// geometric mean of all benchmarks of the metric.
+	GeoMean bool
+}
+
+// WithStatOptions sets the options used to compare the benchmark results.
+func WithStatOptions(opts StatOptions) Option {
+	return func(c *config) {
+		c.stat = opts
+	}
+}
+
+// Validate checks if the options are valid, returning an error if not.
+func (o StatOptions) Validate() error {
+	if o.Alpha < 0 || o.Alpha >= 1 {
+		return fmt.Errorf("stat options: alpha %v must be in the range [0, 1)", o.Alpha)
+	}
+	if _, err := o.Test.deltaTest(); err != nil {
+		return fmt.Errorf("stat options: %v", err)
+	}
+	return nil
+}
+
+// alpha returns the significance level, using DefaultAlpha if none was provided.
+func (o StatOptions) alpha() float64 {
+	if o.Alpha == 0 {
+		return DefaultAlpha
+	}
+	return o.Alpha
+}
+
+// ParseStatTest parses the given string as a StatTest.
+// Valid values are the ones returned by StatTest.String.
+func ParseStatTest(s string) (StatTest, error) {
+	for _, test := range []StatTest{UTest, TTest, NoTest} {
+		if s == test.String() {
+			return test, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid stat test %q", s)
+}
+
+// String returns the string representation of the test.
+func (t StatTest) String() string {
+	switch t {
+	case UTest:
+		return "utest"
+	case TTest:
+		return "ttest"
+	case NoTest:
+		return "none"
+	}
+	return fmt.Sprintf("StatTest(%d)", int(t))
+}
+
+func (t StatTest) deltaTest() (benchstat.DeltaTest, error) {
+	switch t {
+	case UTest:
+		return benchstat.UTest, nil
+	case TTest:
+		return benchstat.TTest, nil
+	case NoTest:
+		return benchstat.NoDeltaTest, nil
+	}
+	return nil, fmt.Errorf("invalid stat test %v", t)
+}
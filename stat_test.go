@@ -0,0 +1,159 @@
+package benchcheck_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/benchcheck"
+	"github.com/madlambda/spells/assert"
+)
+
+func TestStatOptions(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name string
+		opts benchcheck.StatOptions
+		// want are the time/op deltas of each benchmark.
+		want map[string]float64
+	}
+
+	tcases := []testcase{
+		{
+			name: "defaults",
+			want: map[string]float64{
+				"GobEncode":  -13.31,
+				"JSONEncode": 0,
+			},
+		},
+		{
+			name: "t-test",
+			opts: benchcheck.StatOptions{Test: benchcheck.TTest},
+			want: map[string]float64{
+				"GobEncode":  -13.31,
+				"JSONEncode": 0,
+			},
+		},
+		{
+			name: "no test reports all deltas",
+			opts: benchcheck.StatOptions{Test: benchcheck.NoTest},
+			want: map[string]float64{
+				"GobEncode":  -13.31,
+				"JSONEncode": -1.10,
+			},
+		},
+		{
+			name: "stricter alpha",
+			opts: benchcheck.StatOptions{Alpha: 0.001},
+			want: map[string]float64{
+				"GobEncode":  0,
+				"JSONEncode": 0,
+			},
+		},
+		{
+			name: "looser alpha",
+			opts: benchcheck.StatOptions{Alpha: 0.3},
+			want: map[string]float64{
+				"GobEncode":  -13.31,
+				"JSONEncode": -1.10,
+			},
+		},
+		{
+			name: "geomean",
+			opts: benchcheck.StatOptions{GeoMean: true},
+			want: map[string]float64{
+				"GobEncode":  -13.31,
+				"JSONEncode": 0,
+				"[Geo mean]": -7.40,
+			},
+		},
+	}
+
+	for _, tc := range tcases {
+		tcase := tc
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			results := statTestdataFilesWithOptions(t, "old.txt", "new.txt", tcase.opts)
+
+			got := map[string]float64{}
+			for _, result := range results {
+				if result.Metric != benchcheck.TimeMetric {
+					continue
+				}
+				for _, diff := range result.BenchDiffs {
+					got[diff.Name] = diff.Delta
+					if tcase.opts.Test == benchcheck.NoTest && diff.PValue != -1 {
+						t.Errorf("%s: got p-value %v with no test; want -1", diff.Name, diff.PValue)
+					}
+				}
+			}
+
+			if len(got) != len(tcase.want) {
+				t.Fatalf("got deltas %v; want %v", got, tcase.want)
+			}
+			for name, want := range tcase.want {
+				assertEqualWithFloat(t, want, got[name])
+			}
+		})
+	}
+}
+
+func TestStatOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, opts := range []benchcheck.StatOptions{
+		{Alpha: -0.1},
+		{Alpha: 1},
+		{Test: benchcheck.StatTest(42)},
+	} {
+		assert.Error(t, opts.Validate(), "options: %+v", opts)
+
+		_, err := benchcheck.Stat(nil, nil, benchcheck.WithStatOptions(opts))
+		assert.Error(t, err, "Stat with options: %+v", opts)
+
+		_, err = benchcheck.StatModules(benchcheck.Module{}, benchcheck.Module{},
+			benchcheck.WithStatOptions(opts))
+		assert.Error(t, err, "StatModules with options: %+v", opts)
+	}
+}
+
+func TestParseStatTest(t *testing.T) {
+	t.Parallel()
+
+	for _, want := range []benchcheck.StatTest{
+		benchcheck.UTest,
+		benchcheck.TTest,
+		benchcheck.NoTest,
+	} {
+		got, err := benchcheck.ParseStatTest(want.String())
+		assert.NoError(t, err)
+		if got != want {
+			t.Fatalf("ParseStatTest(%q)=%v; want %v", want.String(), got, want)
+		}
+	}
+
+	_, err := benchcheck.ParseStatTest("StoNkS")
+	assert.Error(t, err)
+}
+
+func statTestdataFilesWithOptions(
+	t *testing.T,
+	oldname, newname string,
+	opts benchcheck.StatOptions,
+) []benchcheck.StatResult {
+	t.Helper()
+
+	oldf, err := os.Open(filepath.Join("testdata", oldname))
+	assert.NoError(t, err)
+	defer oldf.Close()
+
+	newf, err := os.Open(filepath.Join("testdata", newname))
+	assert.NoError(t, err)
+	defer newf.Close()
+
+	results, err := benchcheck.StatFiles(oldf, newf, benchcheck.WithStatOptions(opts))
+	assertNoError(t, err)
+	return results
+}
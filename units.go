@@ -0,0 +1,109 @@
+package benchcheck
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+// metricUnits maps the units that can be used on absolute thresholds
+// of each metric to the factor converting them to the unit of the
+// benchmark results, which is the unit benchstat scales from.
+// Metrics not listed here only accept SI prefixes, like benchstat.
+var metricUnits = map[string]map[string]float64{
+	TimeMetric: {
+		"":   1,
+		"ns": 1,
+		"us": 1e3,
+		"µs": 1e3,
+		"ms": 1e6,
+		"s":  1e9,
+	},
+	AllocMetric:  siUnits("B", 1),
+	AllocsMetric: withUnits(siUnits("", 1), siUnits("allocs", 1)),
+	SpeedMetric:  withBaseUnit(siUnits("B/s", 1e-6)),
+}
+
+// siUnits returns the given unit with all SI prefixes benchstat uses
+// when scaling values, including "K" as an alias of "k".
+func siUnits(unit string, factor float64) map[string]float64 {
+	units := map[string]float64{}
+	for prefix, scale := range map[string]float64{
+		"":  1,
+		"k": 1e3,
+		"K": 1e3,
+		"M": 1e6,
+		"G": 1e9,
+		"T": 1e12,
+	} {
+		units[prefix+unit] = scale * factor
+	}
+	return units
+}
+
+// withUnits adds the other units to the given ones.
+func withUnits(units, other map[string]float64) map[string]float64 {
+	for unit, factor := range other {
+		units[unit] = factor
+	}
+	return units
+}
+
+// withBaseUnit adds the empty unit, meaning values on the base unit.
+func withBaseUnit(units map[string]float64) map[string]float64 {
+	units[""] = 1
+	return units
+}
+
+var quantityRe = regexp.MustCompile(`^([+-]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][+-]?[0-9]+)?)(.*)$`)
+
+// parseQuantity parses an absolute value of the given metric, like "1ms"
+// or "2", returning it on the unit of the benchmark results of the metric.
+// The unit is validated against the ones benchstat uses to scale the metric.
+func parseQuantity(metric, val string) (float64, error) {
+	parsed := quantityRe.FindStringSubmatch(strings.TrimSpace(val))
+	if parsed == nil {
+		return 0, fmt.Errorf("invalid value %q", val)
+	}
+	number, err := strconv.ParseFloat(parsed[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q: %v", val, err)
+	}
+
+	units, ok := metricUnits[metric]
+	if !ok {
+		units = siUnits("", 1)
+	}
+	unit := strings.TrimSpace(parsed[2])
+	factor, ok := units[unit]
+	if !ok {
+		valid := make([]string, 0, len(units))
+		for u := range units {
+			if u != "" {
+				valid = append(valid, u)
+			}
+		}
+		sort.Strings(valid)
+		return 0, fmt.Errorf("invalid unit %q for metric %q, valid units: %s",
+			unit, metric, strings.Join(valid, ", "))
+	}
+	return number * factor, nil
+}
+
+// unitless returns true if the value is a plain number, with no unit,
+// like "+10" or "-2.5".
+func unitless(val string) bool {
+	parsed := quantityRe.FindStringSubmatch(strings.TrimSpace(val))
+	return parsed != nil && strings.TrimSpace(parsed[2]) == ""
+}
+
+// formatQuantity formats an absolute value of a benchmark with the
+// given unit, scaling it like benchstat does.
+func formatQuantity(val float64, unit string) string {
+	return benchstat.NewScaler(math.Abs(val), unit)(val)
+}